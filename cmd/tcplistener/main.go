@@ -1,47 +1,72 @@
 package main
 
 import (
+	"crypto/tls"
+	"flag"
 	"fmt"
-	"net"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/madhu1992blue/httpfromtcp/internal/server"
 )
 
-func handleConnection(conn net.Conn) {
-	defer func() {
-		conn.Close()
-		fmt.Println("Connection closed")
-	}()
-	conn.SetDeadline(time.Now().Add(600 * time.Second)) // Set a deadline for the connection to avoid hanging indefinitely.
-	request, err := request.RequestFromReader(conn)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading request: %v\n", err)
-		return
-	}
+func handleRequest(w *response.Writer, request *request.Request) {
 	fmt.Printf(`Request line:
 - Method: %s
 - Target: %s
 - Version: %s`, request.RequestLine.Method, request.RequestLine.RequestTarget, request.RequestLine.HttpVersion)
 	fmt.Println()
+	if request.TLS != nil {
+		fmt.Printf("TLS: %s, %s, SNI %q\n", tls.VersionName(request.TLS.Version), tls.CipherSuiteName(request.TLS.CipherSuite), request.TLS.ServerName)
+	}
+
+	body := []byte("Hello World!\n")
+	if err := w.WriteStatusLine(response.StatusOK); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing status line: %v\n", err)
+		return
+	}
+	if err := w.WriteHeaders(response.GetDefaultHeaders(len(body))); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing headers: %v\n", err)
+		return
+	}
+	if _, err := w.WriteBody(body); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing body: %v\n", err)
+	}
 }
 func main() {
+	port := flag.Int("port", 42069, "TCP port to listen on")
+	certFile := flag.String("tls-cert", "", "PEM certificate file; serves HTTPS together with -tls-key")
+	keyFile := flag.String("tls-key", "", "PEM private key file for -tls-cert")
+	selfSigned := flag.Bool("tls-self-signed", false, "serve HTTPS with an in-memory self-signed certificate for localhost")
+	flag.Parse()
 
-	listener, err := net.Listen("tcp", ":42069")
+	s := server.NewServer(handleRequest)
+	var err error
+	switch {
+	case *certFile != "" || *keyFile != "":
+		err = s.ListenTLS(*port, *certFile, *keyFile)
+	case *selfSigned:
+		var cert tls.Certificate
+		cert, err = server.SelfSignedCertificate("localhost", "127.0.0.1", "::1")
+		if err == nil {
+			s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+			err = s.Listen(*port)
+		}
+	default:
+		err = s.Listen(*port)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error starting TCP listener: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error starting server: %v\n", err)
 		os.Exit(1)
 	}
-	defer listener.Close()
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "listener closed: %v", err)
-			break
-		}
-		fmt.Println("New connection accepted")
-		go handleConnection(conn)
+	fmt.Printf("Server started on %s\n", s.Addr())
 
-	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+	fmt.Println("Shutting down, waiting for open connections")
+	s.Close()
 }
@@ -0,0 +1,86 @@
+// Package chunked holds the pieces of the chunked transfer coding
+// (RFC 9112 §7.1) shared by the request and response parsers and writers.
+package chunked
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+var (
+	// ErrInvalidSize is returned when a chunk-size line does not start with
+	// a valid hexadecimal size.
+	ErrInvalidSize = errors.New("invalid chunk size")
+	// ErrMissingCRLF is returned when chunk data is not followed by CRLF.
+	ErrMissingCRLF = errors.New("missing CRLF after chunk data")
+)
+
+// maxSizeDigits keeps chunk sizes well inside an int.
+const maxSizeDigits = 15
+
+// maxSizeLineBytes bounds a chunk-size line, extensions included, so a line
+// without a CRLF cannot grow the read buffer forever.
+const maxSizeLineBytes = 4096
+
+// IsChunked reports whether the Transfer-Encoding header is present and
+// whether its final coding is chunked. Only then is the body delimited by
+// the chunk framing.
+func IsChunked(h *headers.Headers) (chunked bool, present bool) {
+	value, ok := h.Get("transfer-encoding")
+	if !ok {
+		return false, false
+	}
+	codings := strings.Split(value, ",")
+	last := strings.TrimSpace(codings[len(codings)-1])
+	return strings.EqualFold(last, "chunked"), true
+}
+
+// ParseSize parses a chunk-size line such as "1a;name=value\r\n" and returns
+// the size and the number of bytes consumed. Chunk extensions are ignored.
+// It returns 0 consumed bytes if the line is not complete yet.
+func ParseSize(data []byte) (size int, n int, err error) {
+	crlfIndex := bytes.Index(data, []byte("\r\n"))
+	if crlfIndex == -1 {
+		if len(data) > maxSizeLineBytes {
+			return 0, 0, fmt.Errorf("%w: chunk-size line too long", ErrInvalidSize)
+		}
+		return 0, 0, nil
+	}
+	line := data[:crlfIndex]
+	if i := bytes.IndexByte(line, ';'); i != -1 {
+		line = bytes.TrimRight(line[:i], " \t")
+	}
+	if len(line) == 0 || len(line) > maxSizeDigits {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidSize, data[:crlfIndex])
+	}
+	for _, c := range line {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') && !(c >= 'A' && c <= 'F') {
+			return 0, 0, fmt.Errorf("%w: %q", ErrInvalidSize, data[:crlfIndex])
+		}
+	}
+	parsed, err := strconv.ParseInt(string(line), 16, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidSize, data[:crlfIndex])
+	}
+	return int(parsed), crlfIndex + 2, nil
+}
+
+// ParseDataEnd checks for the CRLF that follows chunk data and returns the
+// number of bytes consumed, or 0 if more data is needed.
+func ParseDataEnd(data []byte) (int, error) {
+	if len(data) < 2 {
+		if len(data) == 1 && data[0] != '\r' {
+			return 0, ErrMissingCRLF
+		}
+		return 0, nil
+	}
+	if data[0] != '\r' || data[1] != '\n' {
+		return 0, ErrMissingCRLF
+	}
+	return 2, nil
+}
@@ -0,0 +1,58 @@
+package chunked
+
+import (
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	// Test: Size with an extension
+	size, n, err := ParseSize([]byte("1a;name=value\r\nrest"))
+	require.NoError(t, err)
+	assert.Equal(t, 26, size)
+	assert.Equal(t, 15, n)
+
+	// Test: Incomplete line
+	_, n, err = ParseSize([]byte("1a"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Test: Invalid sizes
+	for _, line := range []string{"\r\n", "xyz\r\n", "-1\r\n", "+1\r\n", "1000000000000000\r\n"} {
+		_, _, err = ParseSize([]byte(line))
+		assert.ErrorIs(t, err, ErrInvalidSize, line)
+	}
+}
+
+func TestParseDataEnd(t *testing.T) {
+	n, err := ParseDataEnd([]byte("\r\n0\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = ParseDataEnd([]byte("\r"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = ParseDataEnd([]byte("x\r\n"))
+	assert.ErrorIs(t, err, ErrMissingCRLF)
+}
+
+func TestIsChunked(t *testing.T) {
+	h := headers.NewHeaders()
+	isChunked, present := IsChunked(h)
+	assert.False(t, isChunked)
+	assert.False(t, present)
+
+	h.Set("Transfer-Encoding", "gzip, Chunked")
+	isChunked, present = IsChunked(h)
+	assert.True(t, isChunked)
+	assert.True(t, present)
+
+	h.Set("Transfer-Encoding", "chunked, gzip")
+	isChunked, present = IsChunked(h)
+	assert.False(t, isChunked)
+	assert.True(t, present)
+}
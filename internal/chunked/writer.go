@@ -0,0 +1,49 @@
+package chunked
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+// Writer encodes a body in the chunked transfer coding as it is written,
+// one chunk per Write.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write sends p as one chunk. An empty p writes nothing, since a zero-size
+// chunk would end the body.
+func (cw *Writer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	chunk := make([]byte, 0, len(p)+20)
+	chunk = fmt.Appendf(chunk, "%x\r\n", len(p))
+	chunk = append(chunk, p...)
+	chunk = append(chunk, "\r\n"...)
+	if _, err := cw.w.Write(chunk); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the body with the last chunk and the trailer fields, which may
+// be nil.
+func (cw *Writer) Close(trailers *headers.Headers) error {
+	if _, err := io.WriteString(cw.w, "0\r\n"); err != nil {
+		return err
+	}
+	if trailers != nil {
+		if _, err := trailers.WriteTo(cw.w); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(cw.w, "\r\n")
+	return err
+}
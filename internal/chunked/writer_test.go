@@ -0,0 +1,33 @@
+package chunked
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	// Test: One chunk per write, empty writes skipped
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	n, err := w.Write([]byte("hello, "))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	n, err = w.Write(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	_, err = w.Write(bytes.Repeat([]byte("x"), 26))
+	require.NoError(t, err)
+	require.NoError(t, w.Close(nil))
+	assert.Equal(t, "7\r\nhello, \r\n1a\r\n"+string(bytes.Repeat([]byte("x"), 26))+"\r\n0\r\n\r\n", buf.String())
+
+	// Test: Trailers follow the last chunk
+	buf.Reset()
+	trailers := headers.NewHeaders()
+	trailers.Set("X-Checksum", "abc")
+	require.NoError(t, NewWriter(&buf).Close(trailers))
+	assert.Equal(t, "0\r\nX-Checksum: abc\r\n\r\n", buf.String())
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+)
+
+// ErrConnectionClosed is returned by Do once the server has closed the
+// connection, or asked for it to be closed.
+var ErrConnectionClosed = errors.New("connection closed")
+
+// Client sends requests over a single connection and reads the responses,
+// one at a time.
+type Client struct {
+	conn   net.Conn
+	reader *response.Reader
+	// host is the default Host header: the dialed address, or the remote
+	// address of a connection passed to NewClient.
+	host   string
+	closed bool
+}
+
+// Dial connects to addr over TCP.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing %s: %w", addr, err)
+	}
+	c := NewClient(conn)
+	c.host = addr
+	return c, nil
+}
+
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:   conn,
+		reader: response.NewReader(conn),
+		host:   conn.RemoteAddr().String(),
+	}
+}
+
+// NewRequest builds an HTTP/1.1 request ready to be passed to Do.
+func NewRequest(method, target string, body []byte) *request.Request {
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        method,
+			RequestTarget: target,
+			HttpVersion:   "1.1",
+		},
+		Headers:  headers.NewHeaders(),
+		Body:     body,
+		Trailers: headers.NewHeaders(),
+	}
+}
+
+// Do sends req and returns the final response to it. Interim 1xx responses
+// are kept in the response's Interim field. A Host header is added if req
+// has none, and Content-Length is set from the body.
+func (c *Client) Do(req *request.Request) (*response.Response, error) {
+	if c.closed {
+		return nil, ErrConnectionClosed
+	}
+	if _, ok := req.Headers.Get("host"); !ok {
+		req.Headers.Set("Host", c.host)
+	}
+	if len(req.Body) > 0 {
+		req.Headers.Set("Content-Length", strconv.Itoa(len(req.Body)))
+	}
+	if _, err := req.WriteTo(c.conn); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("error writing request: %w", err)
+	}
+	resp, err := c.reader.ReadFinalResponse(req.RequestLine.Method)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if !resp.KeepAlive() {
+		c.Close()
+	}
+	return resp, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
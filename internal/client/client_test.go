@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/madhu1992blue/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoHandler(w *response.Writer, req *request.Request) {
+	body := append([]byte(req.RequestLine.Method+" "+req.RequestLine.RequestTarget+" "), req.Body...)
+	w.WriteStatusLine(response.StatusOK)
+	h := response.GetDefaultHeaders(len(body))
+	if host, ok := req.Headers.Get("host"); ok {
+		h.Set("X-Host", host)
+	}
+	w.WriteHeaders(h)
+	w.WriteBody(body)
+}
+
+func TestClient(t *testing.T) {
+	s, err := server.Serve(0, echoHandler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	c, err := Dial(s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+
+	// Test: GET on a persistent connection
+	resp, err := c.Do(NewRequest("GET", "/coffee", nil))
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "GET /coffee ", string(resp.Body))
+	assert.Equal(t, []string{s.Addr().String()}, resp.Headers.Values("x-host"))
+
+	// Test: POST with a body on the same connection
+	resp, err = c.Do(NewRequest("POST", "/tea", []byte("earl grey")))
+	require.NoError(t, err)
+	assert.Equal(t, "POST /tea earl grey", string(resp.Body))
+
+	// Test: Connection: close ends the client
+	req := NewRequest("GET", "/last", nil)
+	req.Headers.Set("Connection", "close")
+	resp, err = c.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "GET /last ", string(resp.Body))
+	_, err = c.Do(NewRequest("GET", "/", nil))
+	assert.ErrorIs(t, err, ErrConnectionClosed)
+}
@@ -0,0 +1,357 @@
+package fileserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+)
+
+// httpTimeFormat is the IMF-fixdate format of RFC 9110 §5.6.7.
+const httpTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// maxRanges bounds how many ranges one request may ask for. Requests for
+// more are served the whole file, so a client cannot make a small file
+// expand into a huge multipart response.
+const maxRanges = 16
+
+// FileServer serves the files under a root directory. Directories are
+// served through their index.html, and are otherwise not found.
+type FileServer struct {
+	root string
+}
+
+func NewFileServer(root string) *FileServer {
+	return &FileServer{root: root}
+}
+
+// ServeHTTP answers GET and HEAD requests for files. The file path is the
+// router's "*" path value when there is one, so the server can be mounted
+// under a prefix such as "/static/*", and the request path otherwise.
+func (fsrv *FileServer) ServeHTTP(w *response.Writer, req *request.Request) {
+	method := req.RequestLine.Method
+	if method != "GET" && method != "HEAD" {
+		h := headers.NewHeaders()
+		h.Set("Allow", "GET, HEAD")
+		writeError(w, response.StatusMethodNotAllowed, h)
+		return
+	}
+
+	name, ok := req.LookupPathValue("*")
+	if !ok {
+		name = req.RequestLine.Target.Path
+	}
+	file, info, err := fsrv.open(name)
+	if err != nil {
+		var lookupErr *lookupError
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			writeError(w, response.StatusNotFound, nil)
+		case errors.As(err, &lookupErr):
+			writeError(w, response.StatusForbidden, nil)
+		default:
+			writeError(w, response.StatusInternalServerError, nil)
+		}
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(info.Name()))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := headers.NewHeaders()
+	h.Set("Last-Modified", info.ModTime().UTC().Format(httpTimeFormat))
+	h.Set("ETag", etag(info))
+	h.Set("Accept-Ranges", "bytes")
+
+	if notModified(req.Headers, info) {
+		w.WriteStatusLine(response.StatusNotModified)
+		w.WriteHeaders(h)
+		return
+	}
+
+	size := info.Size()
+	ranges, err := rangesFor(req.Headers, info)
+	if err != nil {
+		h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		writeError(w, response.StatusRangeNotSatisfiable, h)
+		return
+	}
+
+	switch len(ranges) {
+	case 0:
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		if method == "GET" {
+			io.Copy(bodyWriter{w}, file)
+		}
+	case 1:
+		r := ranges[0]
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Range", r.contentRange(size))
+		h.Set("Content-Length", strconv.FormatInt(r.length, 10))
+		w.WriteStatusLine(response.StatusPartialContent)
+		w.WriteHeaders(h)
+		if method == "GET" {
+			io.Copy(bodyWriter{w}, io.NewSectionReader(file, r.start, r.length))
+		}
+	default:
+		writeMultipart(w, h, method, file, ranges, contentType, size)
+	}
+}
+
+// lookupError is returned by open when name exists but cannot be served
+// from inside the root, such as a symlink that leads out of it.
+type lookupError struct {
+	err error
+}
+
+func (e *lookupError) Error() string {
+	return e.err.Error()
+}
+
+func (e *lookupError) Unwrap() error {
+	return e.err
+}
+
+// open resolves name inside the root and opens it. Cleaning name as an
+// absolute path first drops every ".." that would climb above the root, and
+// resolving it through an os.Root keeps symlinks from leading out of it.
+func (fsrv *FileServer) open(name string) (*os.File, fs.FileInfo, error) {
+	if strings.ContainsRune(name, 0) {
+		return nil, nil, fs.ErrNotExist
+	}
+	root, err := os.OpenRoot(fsrv.root)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer root.Close()
+
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if clean == "" {
+		clean = "."
+	}
+	info, err := stat(root, clean)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		clean = path.Join(clean, "index.html")
+		if info, err = stat(root, clean); err != nil {
+			return nil, nil, err
+		}
+		if info.IsDir() {
+			return nil, nil, fs.ErrNotExist
+		}
+	}
+	file, err := root.Open(filepath.FromSlash(clean))
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// stat looks name up inside root. Failures other than a missing file are
+// returned as a *lookupError.
+func stat(root *os.Root, name string) (fs.FileInfo, error) {
+	info, err := root.Stat(filepath.FromSlash(name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, &lookupError{err: err}
+	}
+	return info, err
+}
+
+// etag builds a strong validator from the modification time and size,
+// which change whenever the file is rewritten.
+func etag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is
+// no If-None-Match, as RFC 9110 §13.2.2 orders them.
+func notModified(h *headers.Headers, info fs.FileInfo) bool {
+	if ifNoneMatch, ok := h.Get("if-none-match"); ok {
+		return etagListMatches(ifNoneMatch, etag(info))
+	}
+	if ifModifiedSince, ok := h.Get("if-modified-since"); ok {
+		since, err := time.Parse(httpTimeFormat, ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		return !info.ModTime().Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// etagListMatches reports whether a comma-separated list of entity tags, or
+// "*", matches tag using weak comparison.
+func etagListMatches(list, tag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// byteRange is a satisfiable range of a file, with an inclusive start.
+type byteRange struct {
+	start, length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+var errUnsatisfiable = errors.New("range not satisfiable")
+
+// rangesFor returns the ranges a request asks for, or none if the whole
+// file should be sent: when there is no Range header, when its unit is not
+// bytes or it is malformed, or when If-Range no longer matches the file.
+// It fails only if the header is valid but no range overlaps the file.
+func rangesFor(h *headers.Headers, info fs.FileInfo) ([]byteRange, error) {
+	value, ok := h.Get("range")
+	if !ok {
+		return nil, nil
+	}
+	if ifRange, ok := h.Get("if-range"); ok && ifRange != etag(info) &&
+		ifRange != info.ModTime().UTC().Format(httpTimeFormat) {
+		return nil, nil
+	}
+	specs, ok := strings.CutPrefix(value, "bytes=")
+	if !ok {
+		return nil, nil
+	}
+	size := info.Size()
+	var ranges []byteRange
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, nil
+		}
+		var r byteRange
+		if first == "" {
+			// A suffix range: the last n bytes.
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, nil
+			}
+			// A file shorter than n is sent whole, and an empty one has no
+			// bytes to satisfy the range with.
+			n = min(n, size)
+			if n == 0 {
+				continue
+			}
+			r = byteRange{start: size - n, length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+			end := size - 1
+			if last != "" {
+				end, err = strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, nil
+				}
+				end = min(end, size-1)
+			}
+			if start >= size {
+				continue
+			}
+			r = byteRange{start: start, length: end - start + 1}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) > maxRanges {
+		return nil, nil
+	}
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiable
+	}
+	return ranges, nil
+}
+
+// writeMultipart answers with a multipart/byteranges body holding each range
+// as its own part, as described in RFC 9110 §14.6.
+func writeMultipart(w *response.Writer, h *headers.Headers, method string, file io.ReaderAt, ranges []byteRange, contentType string, size int64) {
+	boundary := newBoundary()
+	partHeaders := make([]string, len(ranges))
+	total := int64(0)
+	for i, r := range ranges {
+		partHeaders[i] = fmt.Sprintf("--%s\r\nContent-Type: %s\r\nContent-Range: %s\r\n\r\n", boundary, contentType, r.contentRange(size))
+		total += int64(len(partHeaders[i])) + r.length + 2
+	}
+	closing := fmt.Sprintf("--%s--\r\n", boundary)
+	total += int64(len(closing))
+
+	h.Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	h.Set("Content-Length", strconv.FormatInt(total, 10))
+	w.WriteStatusLine(response.StatusPartialContent)
+	w.WriteHeaders(h)
+	if method != "GET" {
+		return
+	}
+	for i, r := range ranges {
+		w.WriteBody([]byte(partHeaders[i]))
+		if _, err := io.Copy(bodyWriter{w}, io.NewSectionReader(file, r.start, r.length)); err != nil {
+			return
+		}
+		w.WriteBody([]byte("\r\n"))
+	}
+	w.WriteBody([]byte(closing))
+}
+
+func newBoundary() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// bodyWriter lets io.Copy stream into a response body.
+type bodyWriter struct {
+	w *response.Writer
+}
+
+func (b bodyWriter) Write(p []byte) (int, error) {
+	return b.w.WriteBody(p)
+}
+
+// writeError answers with statusCode and its reason phrase as the body,
+// on top of any headers already prepared in h.
+func writeError(w *response.Writer, statusCode response.StatusCode, h *headers.Headers) {
+	body := []byte(response.ReasonPhrase(statusCode) + "\n")
+	if h == nil {
+		h = headers.NewHeaders()
+	}
+	h.Set("Content-Type", "text/plain")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	if err := w.WriteStatusLine(statusCode); err != nil {
+		return
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return
+	}
+	w.WriteBody(body)
+}
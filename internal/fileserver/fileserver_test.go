@@ -0,0 +1,288 @@
+package fileserver
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/madhu1992blue/httpfromtcp/internal/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var modTime = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// newRoot creates a directory tree for the tests to serve.
+func newRoot(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0o755))
+	files := map[string]string{
+		"hello.txt":       "Hello, World!\n",
+		"digits.bin":      "0123456789",
+		"docs/index.html": "<h1>docs</h1>",
+		"../secret.txt":   "top secret",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	return root
+}
+
+// serve sends a request through fsrv and parses what it writes back.
+func serve(t *testing.T, fsrv *FileServer, method, target string, fields ...string) *response.Response {
+	t.Helper()
+	raw := method + " " + target + " HTTP/1.1\r\n"
+	for _, field := range fields {
+		raw += field + "\r\n"
+	}
+	req, err := request.RequestFromReader(strings.NewReader(raw + "\r\n"))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	fsrv.ServeHTTP(response.NewWriter(&buf), req)
+	resp, err := response.NewReader(&buf).ReadResponse(method)
+	require.NoError(t, err)
+	return resp
+}
+
+func header(resp *response.Response, key string) string {
+	value, _ := resp.Headers.Get(key)
+	return value
+}
+
+func TestServeFile(t *testing.T) {
+	fsrv := NewFileServer(newRoot(t))
+
+	// Test: Whole file with validators
+	resp := serve(t, fsrv, "GET", "/hello.txt")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "Hello, World!\n", string(resp.Body))
+	assert.Equal(t, mime.TypeByExtension(".txt"), header(resp, "Content-Type"))
+	assert.Equal(t, "14", header(resp, "Content-Length"))
+	assert.Equal(t, "Fri, 01 Mar 2024 12:00:00 GMT", header(resp, "Last-Modified"))
+	assert.NotEmpty(t, header(resp, "ETag"))
+	assert.Equal(t, "bytes", header(resp, "Accept-Ranges"))
+
+	// Test: Unknown extensions are served as octet-stream
+	resp = serve(t, fsrv, "GET", "/digits.bin")
+	assert.Equal(t, "application/octet-stream", header(resp, "Content-Type"))
+
+	// Test: HEAD sends the headers only
+	resp = serve(t, fsrv, "HEAD", "/hello.txt")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "14", header(resp, "Content-Length"))
+	assert.Empty(t, resp.Body)
+
+	// Test: Directory index
+	resp = serve(t, fsrv, "GET", "/docs/")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "<h1>docs</h1>", string(resp.Body))
+	assert.Equal(t, "text/html; charset=utf-8", header(resp, "Content-Type"))
+
+	// Test: Directory without index and missing file
+	resp = serve(t, fsrv, "GET", "/empty/")
+	assert.Equal(t, response.StatusNotFound, resp.StatusLine.StatusCode)
+	resp = serve(t, fsrv, "GET", "/missing.txt")
+	assert.Equal(t, response.StatusNotFound, resp.StatusLine.StatusCode)
+
+	// Test: Other methods
+	resp = serve(t, fsrv, "POST", "/hello.txt")
+	assert.Equal(t, response.StatusMethodNotAllowed, resp.StatusLine.StatusCode)
+	assert.Equal(t, "GET, HEAD", header(resp, "Allow"))
+}
+
+func TestPathTraversal(t *testing.T) {
+	fsrv := NewFileServer(newRoot(t))
+
+	// Test: Dot segments cannot leave the root
+	for _, target := range []string{"/../secret.txt", "/docs/../../secret.txt", "/%2e%2e/secret.txt", "/..%2fsecret.txt"} {
+		resp := serve(t, fsrv, "GET", target)
+		assert.Equal(t, response.StatusNotFound, resp.StatusLine.StatusCode, target)
+		assert.NotContains(t, string(resp.Body), "top secret", target)
+	}
+
+	// Test: Dot segments inside the root still resolve
+	resp := serve(t, fsrv, "GET", "/docs/../hello.txt")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+
+	// Test: A router's "*" path value is confined too
+	req, err := request.RequestFromReader(strings.NewReader("GET /static/x HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	req.SetPathValue("*", "../secret.txt")
+	var buf bytes.Buffer
+	fsrv.ServeHTTP(response.NewWriter(&buf), req)
+	got, err := response.ResponseFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusNotFound, got.StatusLine.StatusCode)
+
+	// Test: Symlinks cannot lead out of the root
+	root := newRoot(t)
+	require.NoError(t, os.Symlink("../secret.txt", filepath.Join(root, "leak.txt")))
+	require.NoError(t, os.Symlink("hello.txt", filepath.Join(root, "alias.txt")))
+	fsrv = NewFileServer(root)
+	resp = serve(t, fsrv, "GET", "/leak.txt")
+	assert.Equal(t, response.StatusForbidden, resp.StatusLine.StatusCode)
+	assert.NotContains(t, string(resp.Body), "top secret")
+
+	// Test: Symlinks inside the root are followed
+	resp = serve(t, fsrv, "GET", "/alias.txt")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "Hello, World!\n", string(resp.Body))
+}
+
+func TestMountedUnderPrefix(t *testing.T) {
+	root := newRoot(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>home</h1>"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "static"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "static", "index.html"), []byte("<h1>wrong</h1>"), 0o644))
+	rt := router.NewRouter()
+	rt.Handle("GET", "/static/*", NewFileServer(root).ServeHTTP)
+
+	get := func(target string) *response.Response {
+		req, err := request.RequestFromReader(strings.NewReader("GET " + target + " HTTP/1.1\r\n\r\n"))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		rt.ServeHTTP(response.NewWriter(&buf), req)
+		resp, err := response.ResponseFromReader(&buf)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Test: Files resolve relative to the root, not the full path
+	resp := get("/static/hello.txt")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "Hello, World!\n", string(resp.Body))
+
+	// Test: An empty "*" serves the root index
+	for _, target := range []string{"/static/", "/static"} {
+		resp = get(target)
+		assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode, target)
+		assert.Equal(t, "<h1>home</h1>", string(resp.Body), target)
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	fsrv := NewFileServer(newRoot(t))
+	etag := header(serve(t, fsrv, "GET", "/hello.txt"), "ETag")
+
+	// Test: Matching If-None-Match
+	resp := serve(t, fsrv, "GET", "/hello.txt", "If-None-Match: "+etag)
+	assert.Equal(t, response.StatusNotModified, resp.StatusLine.StatusCode)
+	assert.Empty(t, resp.Body)
+	assert.Equal(t, etag, header(resp, "ETag"))
+
+	// Test: Weak and listed entity tags match too
+	resp = serve(t, fsrv, "GET", "/hello.txt", `If-None-Match: "other", W/`+etag)
+	assert.Equal(t, response.StatusNotModified, resp.StatusLine.StatusCode)
+	resp = serve(t, fsrv, "GET", "/hello.txt", "If-None-Match: *")
+	assert.Equal(t, response.StatusNotModified, resp.StatusLine.StatusCode)
+
+	// Test: Different If-None-Match
+	resp = serve(t, fsrv, "GET", "/hello.txt", `If-None-Match: "other"`)
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+
+	// Test: If-Modified-Since
+	resp = serve(t, fsrv, "GET", "/hello.txt", "If-Modified-Since: Fri, 01 Mar 2024 12:00:00 GMT")
+	assert.Equal(t, response.StatusNotModified, resp.StatusLine.StatusCode)
+	resp = serve(t, fsrv, "GET", "/hello.txt", "If-Modified-Since: Fri, 01 Mar 2024 11:59:59 GMT")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+
+	// Test: If-None-Match takes precedence over If-Modified-Since
+	resp = serve(t, fsrv, "GET", "/hello.txt", `If-None-Match: "other"`, "If-Modified-Since: Fri, 01 Mar 2024 12:00:00 GMT")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+}
+
+func TestRange(t *testing.T) {
+	fsrv := NewFileServer(newRoot(t))
+
+	tests := []struct {
+		rangeHeader  string
+		body         string
+		contentRange string
+	}{
+		{"bytes=0-3", "0123", "bytes 0-3/10"},
+		{"bytes=7-", "789", "bytes 7-9/10"},
+		{"bytes=-2", "89", "bytes 8-9/10"},
+		{"bytes=5-100", "56789", "bytes 5-9/10"},
+		{"bytes=-50", "0123456789", "bytes 0-9/10"},
+	}
+	for _, tt := range tests {
+		// Test: Single range
+		resp := serve(t, fsrv, "GET", "/digits.bin", "Range: "+tt.rangeHeader)
+		assert.Equal(t, response.StatusPartialContent, resp.StatusLine.StatusCode, tt.rangeHeader)
+		assert.Equal(t, tt.body, string(resp.Body), tt.rangeHeader)
+		assert.Equal(t, tt.contentRange, header(resp, "Content-Range"), tt.rangeHeader)
+	}
+
+	// Test: Unsatisfiable range
+	resp := serve(t, fsrv, "GET", "/digits.bin", "Range: bytes=10-20")
+	assert.Equal(t, response.StatusRangeNotSatisfiable, resp.StatusLine.StatusCode)
+	assert.Equal(t, "bytes */10", header(resp, "Content-Range"))
+
+	// Test: A suffix range of an empty file is unsatisfiable
+	emptyRoot := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(emptyRoot, "empty.txt"), nil, 0o644))
+	resp = serve(t, NewFileServer(emptyRoot), "GET", "/empty.txt", "Range: bytes=-5")
+	assert.Equal(t, response.StatusRangeNotSatisfiable, resp.StatusLine.StatusCode)
+	assert.Equal(t, "bytes */0", header(resp, "Content-Range"))
+
+	// Test: Malformed or non-byte ranges are ignored
+	for _, value := range []string{"bytes=3-1", "bytes=a-b", "items=0-1"} {
+		resp = serve(t, fsrv, "GET", "/digits.bin", "Range: "+value)
+		assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode, value)
+		assert.Equal(t, "0123456789", string(resp.Body), value)
+	}
+
+	// Test: If-Range
+	etag := header(resp, "ETag")
+	resp = serve(t, fsrv, "GET", "/digits.bin", "Range: bytes=0-1", "If-Range: "+etag)
+	assert.Equal(t, response.StatusPartialContent, resp.StatusLine.StatusCode)
+	resp = serve(t, fsrv, "GET", "/digits.bin", "Range: bytes=0-1", `If-Range: "stale"`)
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+}
+
+func TestMultipartRange(t *testing.T) {
+	fsrv := NewFileServer(newRoot(t))
+
+	// Test: Multiple ranges become multipart/byteranges
+	resp := serve(t, fsrv, "GET", "/digits.bin", "Range: bytes=0-1, 4-5, -1")
+	assert.Equal(t, response.StatusPartialContent, resp.StatusLine.StatusCode)
+	mediaType, params, err := mime.ParseMediaType(header(resp, "Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+
+	mr := multipart.NewReader(bytes.NewReader(resp.Body), params["boundary"])
+	want := []struct{ body, contentRange string }{
+		{"01", "bytes 0-1/10"},
+		{"45", "bytes 4-5/10"},
+		{"9", "bytes 9-9/10"},
+	}
+	for _, w := range want {
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "application/octet-stream", part.Header.Get("Content-Type"))
+		assert.Equal(t, w.contentRange, part.Header.Get("Content-Range"))
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		assert.Equal(t, w.body, string(body))
+	}
+	_, err = mr.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Test: Unsatisfiable ranges are dropped from the list
+	resp = serve(t, fsrv, "GET", "/digits.bin", "Range: bytes=0-1, 50-60")
+	assert.Equal(t, response.StatusPartialContent, resp.StatusLine.StatusCode)
+	assert.Equal(t, "01", string(resp.Body))
+}
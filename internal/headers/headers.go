@@ -2,33 +2,207 @@ package headers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"strings"
 )
 
-type Headers map[string]string
+// field is a single field line. The name keeps the casing it was received
+// or set with; lookups ignore case.
+type field struct {
+	name  string
+	value string
+}
+
+// Headers holds the field lines of a message in the order they were parsed or
+// added. A field may appear more than once, as Set-Cookie often does.
+type Headers struct {
+	fields []field
+}
+
+func NewHeaders() *Headers {
+	return &Headers{}
+}
+
+// Get returns the value of the header with the given key. Keys are matched
+// case-insensitively. When the field appears more than once the values are
+// combined with ", ", which is only meaningful for list-based fields; use
+// Values for fields such as Set-Cookie.
+func (h *Headers) Get(key string) (string, bool) {
+	values := h.Values(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return strings.Join(values, ", "), true
+}
+
+// Values returns every value of the header with the given key, in order.
+func (h *Headers) Values(key string) []string {
+	var values []string
+	for _, f := range h.fields {
+		if strings.EqualFold(f.name, key) {
+			values = append(values, f.value)
+		}
+	}
+	return values
+}
+
+// Set stores value under key, replacing any existing values. The field keeps
+// the position of its first occurrence.
+func (h *Headers) Set(key, value string) {
+	replaced := false
+	fields := h.fields[:0]
+	for _, f := range h.fields {
+		if !strings.EqualFold(f.name, key) {
+			fields = append(fields, f)
+			continue
+		}
+		if !replaced {
+			fields = append(fields, field{name: key, value: value})
+			replaced = true
+		}
+	}
+	h.fields = fields
+	if !replaced {
+		h.Add(key, value)
+	}
+}
+
+// initialFields is room for the field lines of a typical request, so that
+// parsing one grows the slice once rather than for every few fields.
+const initialFields = 16
+
+// Add appends a field line, keeping any existing values for key.
+func (h *Headers) Add(key, value string) {
+	if h.fields == nil {
+		h.fields = make([]field, 0, initialFields)
+	}
+	h.fields = append(h.fields, field{name: key, value: value})
+}
+
+// AppendToLast appends value to the field added last, after a single space,
+// the way an obs-fold continuation line extends the field above it. It
+// reports false if there is no field yet.
+func (h *Headers) AppendToLast(value string) bool {
+	if len(h.fields) == 0 {
+		return false
+	}
+	last := &h.fields[len(h.fields)-1]
+	if last.value == "" {
+		last.value = value
+	} else if value != "" {
+		last.value += " " + value
+	}
+	return true
+}
 
-func NewHeaders() Headers {
-	return make(Headers)
+// Last returns the field added last, as the parser has just read it. It
+// reports false if there is no field yet.
+func (h *Headers) Last() (name, value string, ok bool) {
+	if len(h.fields) == 0 {
+		return "", "", false
+	}
+	last := h.fields[len(h.fields)-1]
+	return last.name, last.value, true
+}
+
+// Del removes every value of the header with the given key.
+func (h *Headers) Del(key string) {
+	fields := h.fields[:0]
+	for _, f := range h.fields {
+		if !strings.EqualFold(f.name, key) {
+			fields = append(fields, f)
+		}
+	}
+	h.fields = fields
+}
+
+// Len returns the number of field lines.
+func (h *Headers) Len() int {
+	return len(h.fields)
+}
+
+// Clone returns a copy of the field lines that can be changed without
+// affecting h.
+func (h *Headers) Clone() *Headers {
+	return &Headers{fields: append([]field(nil), h.fields...)}
+}
+
+// All iterates over the field lines in order, yielding each name with its
+// original casing and its value.
+func (h *Headers) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for _, f := range h.fields {
+			if !yield(f.name, f.value) {
+				return
+			}
+		}
+	}
 }
 
+// WriteTo writes each field line as "Name: value\r\n" in order. It does not
+// write the empty line that ends a header section.
+func (h *Headers) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, f := range h.fields {
+		n, err := fmt.Fprintf(w, "%s: %s\r\n", f.name, f.value)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// HasToken reports whether the comma-separated list in the header with the
+// given key contains token. Tokens are compared case-insensitively, as for
+// Connection or Transfer-Encoding.
+func (h *Headers) HasToken(key, token string) bool {
+	value, ok := h.Get(key)
+	if !ok {
+		return false
+	}
+	for _, item := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrInvalidFieldLine = errors.New("invalid header line")
+	ErrInvalidFieldName = errors.New("invalid header name")
+	// ErrSpaceBeforeColon is returned for whitespace between a field name
+	// and its colon, which RFC 9112 §5.1 requires servers to reject.
+	ErrSpaceBeforeColon = errors.New("whitespace before colon in header line")
+)
+
 const headerSpecialChars = "!#$%&'*+-.^_`|~"
 
-func validateHeaderKey(key string) error {
+func validateHeaderKey(key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("%w: empty", ErrInvalidFieldName)
+	}
 	for _, c := range key {
 		if !(c >= 'a' && c <= 'z') &&
 			!(c >= 'A' && c <= 'Z') &&
 			!(c >= '0' && c <= '9') &&
-			!strings.Contains(headerSpecialChars, string(c)) {
+			strings.IndexByte(headerSpecialChars, c) == -1 {
 
-			return fmt.Errorf("invalid character in header key: %c", c)
+			return fmt.Errorf("%w: invalid character %q in %q", ErrInvalidFieldName, rune(c), key)
 		}
 	}
 	return nil
 }
 
-func (h Headers) Parse(data []byte) (n int, done bool, err error) {
-	n = 0
+// Parse parses one field line from the start of data, or the empty line
+// that ends the header section. The line is examined in place; only the
+// name and value that are kept are copied out of data, and a common name
+// not even that.
+func (h *Headers) Parse(data []byte) (n int, done bool, err error) {
 	crlfIndex := bytes.Index(data, []byte("\r\n"))
 	if crlfIndex == -1 {
 		// Not enough data to parse the headers
@@ -38,24 +212,18 @@ func (h Headers) Parse(data []byte) (n int, done bool, err error) {
 		// Empty headers, done parsing
 		return 2, true, nil
 	}
-	headerLine := string(data[:crlfIndex])
-	parts := bytes.SplitN(data[:crlfIndex], []byte(":"), 2)
-	if len(parts) != 2 {
-		return 0, false, fmt.Errorf("invalid header line: %s", headerLine)
-	}
-	if bytes.HasSuffix(parts[0], []byte(" ")) {
-		return 0, false, fmt.Errorf("invalid spacing in key: %s", headerLine)
+	line := data[:crlfIndex]
+	rawKey, rawValue, ok := bytes.Cut(line, []byte(":"))
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %q", ErrInvalidFieldLine, line)
 	}
-	key := strings.ToLower(string(bytes.TrimSpace(parts[0])))
-	value := string(bytes.TrimSpace(parts[1]))
-	if validateHeaderKey(key) != nil {
-		return 0, false, fmt.Errorf("invalid header key: %s", key)
+	if bytes.HasSuffix(rawKey, []byte(" ")) || bytes.HasSuffix(rawKey, []byte("\t")) {
+		return 0, false, fmt.Errorf("%w: %q", ErrSpaceBeforeColon, line)
 	}
-	prevHeaderValue, exists := h[key]
-	if exists {
-		value = fmt.Sprintf("%s, %s", prevHeaderValue, value)
+	key := bytes.TrimSpace(rawKey)
+	if err := validateHeaderKey(key); err != nil {
+		return 0, false, err
 	}
-	h[key] = value
-	n = crlfIndex + 2
-	return n, false, nil
+	h.Add(internName(key), string(bytes.TrimSpace(rawValue)))
+	return crlfIndex + 2, false, nil
 }
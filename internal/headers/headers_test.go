@@ -1,6 +1,7 @@
 package headers
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +15,7 @@ func TestRequestLineParse(t *testing.T) {
 	n, done, err := headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069", headers["host"])
+	assert.Equal(t, []string{"localhost:42069"}, headers.Values("host"))
 	assert.Equal(t, 23, n)
 	assert.False(t, done)
 
@@ -23,16 +24,22 @@ func TestRequestLineParse(t *testing.T) {
 	data = []byte("       Host : localhost:42069       \r\n\r\n")
 	n, done, err = headers.Parse(data)
 	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrSpaceBeforeColon)
 	assert.Equal(t, 0, n)
 	assert.False(t, done)
 
+	// Test: Tab before colon
+	headers = NewHeaders()
+	_, _, err = headers.Parse([]byte("Host\t: localhost:42069\r\n\r\n"))
+	assert.ErrorIs(t, err, ErrSpaceBeforeColon)
+
 	// Valid single header
 	headers = NewHeaders()
 	data = []byte("Host:localhost:42069\r\n\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069", headers["host"])
+	assert.Equal(t, []string{"localhost:42069"}, headers.Values("host"))
 	assert.Equal(t, 22, n)
 	assert.False(t, done)
 
@@ -42,7 +49,7 @@ func TestRequestLineParse(t *testing.T) {
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069", headers["host"])
+	assert.Equal(t, []string{"localhost:42069"}, headers.Values("host"))
 	assert.Equal(t, 24, n)
 	assert.False(t, done)
 
@@ -52,7 +59,7 @@ func TestRequestLineParse(t *testing.T) {
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069", headers["host"])
+	assert.Equal(t, []string{"localhost:42069"}, headers.Values("host"))
 	require.Equal(t, n, 23)
 	assert.False(t, done)
 
@@ -61,7 +68,7 @@ func TestRequestLineParse(t *testing.T) {
 	data = []byte("\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
-	require.Empty(t, headers)
+	assert.Equal(t, 0, headers.Len())
 	assert.Equal(t, 2, n)
 	assert.True(t, done)
 
@@ -78,19 +85,124 @@ func TestRequestLineParse(t *testing.T) {
 	data = []byte("H@st: localhost:42069\r\nuser-agent: chrome\r\n\r\n")
 	n, done, err = headers.Parse(data)
 	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidFieldName)
 	assert.Equal(t, 0, n)
 	assert.False(t, done)
 
 	// Multiple values for a single header
-	headers = map[string]string{
-		"set-person": "MN",
-	}
+	headers = NewHeaders()
+	headers.Add("set-person", "MN")
 	data = []byte("Set-Person: Madhusudan N\r\n\r\n")
 	n, done, err = headers.Parse(data)
 	require.NoError(t, err)
 	require.NotNil(t, headers)
-	assert.Equal(t, "MN, Madhusudan N", headers["set-person"])
+	assert.Equal(t, []string{"MN", "Madhusudan N"}, headers.Values("set-person"))
+	value, ok := headers.Get("Set-Person")
+	assert.True(t, ok)
+	assert.Equal(t, "MN, Madhusudan N", value)
 	assert.Equal(t, 26, n)
 	assert.False(t, done)
 
 }
+
+func TestHeadersAPI(t *testing.T) {
+	// Test: Values that contain commas are kept apart
+	headers := NewHeaders()
+	data := []byte("Set-Cookie: a=1; Expires=Wed, 21 Oct 2015 07:28:00 GMT\r\nHost: example.com\r\nset-cookie: b=2\r\n\r\n")
+	total := 0
+	for {
+		n, done, err := headers.Parse(data[total:])
+		require.NoError(t, err)
+		total += n
+		if done {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a=1; Expires=Wed, 21 Oct 2015 07:28:00 GMT", "b=2"}, headers.Values("SET-COOKIE"))
+
+	// Test: Serialization keeps order and casing
+	var buf bytes.Buffer
+	_, err := headers.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, string(data[:len(data)-2]), buf.String())
+
+	// Test: Set replaces every value in place of the first one
+	headers.Set("Set-Cookie", "c=3")
+	assert.Equal(t, []string{"c=3"}, headers.Values("set-cookie"))
+	names := []string{}
+	for name := range headers.All() {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"Set-Cookie", "Host"}, names)
+
+	// Test: Add keeps existing values
+	headers.Add("Vary", "Accept")
+	headers.Add("vary", "Origin")
+	assert.Equal(t, []string{"Accept", "Origin"}, headers.Values("Vary"))
+	assert.True(t, headers.HasToken("VARY", "origin"))
+
+	// Test: Del removes every value
+	headers.Del("VARY")
+	_, ok := headers.Get("vary")
+	assert.False(t, ok)
+	assert.Equal(t, 2, headers.Len())
+
+	// Test: AppendToLast continues the last field
+	empty := NewHeaders()
+	assert.False(t, empty.AppendToLast("orphan"))
+	empty.Add("X-Long", "first")
+	assert.True(t, empty.AppendToLast("second"))
+	long, _ := empty.Get("x-long")
+	assert.Equal(t, "first second", long)
+
+	// Test: Last returns the field added last
+	name, last, ok := empty.Last()
+	assert.True(t, ok)
+	assert.Equal(t, "X-Long", name)
+	assert.Equal(t, "first second", last)
+	_, _, ok = NewHeaders().Last()
+	assert.False(t, ok)
+
+	// Test: Set adds a missing field at the end
+	headers.Set("Content-Type", "text/plain")
+	value, ok := headers.Get("content-type")
+	assert.True(t, ok)
+	assert.Equal(t, "text/plain", value)
+	assert.Equal(t, 3, headers.Len())
+
+	// Test: Clone is independent of the original
+	clone := headers.Clone()
+	clone.Set("Connection", "close")
+	clone.Set("Host", "other.example")
+	_, ok = headers.Get("connection")
+	assert.False(t, ok)
+	host, _ := headers.Get("host")
+	assert.Equal(t, "example.com", host)
+	assert.Equal(t, 4, clone.Len())
+}
+
+func TestParseInternsNames(t *testing.T) {
+	// Test: Common names keep the casing they were sent with
+	h := NewHeaders()
+	_, _, err := h.Parse([]byte("content-type: text/plain\r\n"))
+	require.NoError(t, err)
+	_, _, err = h.Parse([]byte("X-Custom: yes\r\n"))
+	require.NoError(t, err)
+	var names []string
+	for name := range h.All() {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"content-type", "X-Custom"}, names)
+
+	// Test: Parsing a common field copies only its value
+	line := []byte("User-Agent: curl/8.5.0\r\n")
+	h = NewHeaders()
+	h.Add("Host", "localhost")
+	allocs := testing.AllocsPerRun(100, func() {
+		h.fields = h.fields[:1]
+		if _, _, err := h.Parse(line); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 1.0, allocs)
+}
@@ -0,0 +1,77 @@
+package headers
+
+import "strings"
+
+// commonNames are field names most requests and responses carry. Parsing
+// one of them, in this casing or all lower case, reuses the string here
+// instead of allocating a new one.
+var commonNames = []string{
+	"Accept",
+	"Accept-Charset",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Accept-Ranges",
+	"Authorization",
+	"Cache-Control",
+	"Connection",
+	"Content-Encoding",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"Cookie",
+	"Date",
+	"DNT",
+	"ETag",
+	"Expect",
+	"Host",
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Range",
+	"Keep-Alive",
+	"Last-Modified",
+	"Location",
+	"Origin",
+	"Pragma",
+	"Priority",
+	"Proxy-Connection",
+	"Range",
+	"Referer",
+	"Sec-Fetch-Dest",
+	"Sec-Fetch-Mode",
+	"Sec-Fetch-Site",
+	"Sec-Fetch-User",
+	"Server",
+	"Set-Cookie",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Upgrade-Insecure-Requests",
+	"User-Agent",
+	"Vary",
+	"Via",
+	"X-Forwarded-For",
+	"X-Forwarded-Proto",
+	"X-Request-Id",
+}
+
+var internedNames = func() map[string]string {
+	m := make(map[string]string, 2*len(commonNames))
+	for _, name := range commonNames {
+		m[name] = name
+		lower := strings.ToLower(name)
+		m[lower] = lower
+	}
+	return m
+}()
+
+// internName returns b as a string, shared with every other field of that
+// name when it is a common one. The casing is kept as sent.
+func internName(b []byte) string {
+	// The compiler does not allocate for a string(b) used only as a map key.
+	if name, ok := internedNames[string(b)]; ok {
+		return name
+	}
+	return string(b)
+}
@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/chunked"
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+)
+
+// viaPseudonym names this proxy in the Via header.
+const viaPseudonym = "httpfromtcp"
+
+const dialTimeout = 10 * time.Second
+
+// defaultTimeout is the Timeout of a new Proxy, in line with the server's
+// ReadTimeout.
+const defaultTimeout = 60 * time.Second
+
+// copyBufferSize is the most body bytes relayed to the client in one write.
+const copyBufferSize = 32 << 10
+
+// hopByHopHeaders only apply to a single connection, so they are never
+// forwarded (RFC 9110 §7.6.1). Fields named in the Connection header are
+// dropped as well.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"TE",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Proxy forwards each request to an upstream server over a new connection
+// and streams the upstream response back as it arrives. Bodies without a
+// Content-Length are relayed chunk by chunk, along with any trailers the
+// upstream announced. Request bodies are streamed upstream the same way
+// when the server streams them, see request.ParserOptions.StreamBody.
+type Proxy struct {
+	// Timeout bounds how long the proxy waits for the upstream to take the
+	// request or to send the next bytes of its response, so a stalled
+	// upstream cannot hold the handler forever. Zero means no limit.
+	Timeout time.Duration
+
+	upstream string
+}
+
+// NewProxy returns a proxy forwarding to the TCP address upstream, such as
+// "localhost:8080".
+func NewProxy(upstream string) *Proxy {
+	return &Proxy{upstream: upstream, Timeout: defaultTimeout}
+}
+
+// ServeHTTP forwards req upstream. If the upstream cannot be reached or
+// sends an invalid response, the client gets a 502 Bad Gateway. If the
+// upstream fails once the response has started, the client connection is
+// closed after what was relayed, so the client sees the body cut short.
+func (p *Proxy) ServeHTTP(w *response.Writer, req *request.Request) {
+	netConn, err := net.DialTimeout("tcp", p.upstream, dialTimeout)
+	if err != nil {
+		writeError(w, response.StatusBadGateway)
+		return
+	}
+	defer netConn.Close()
+	conn := &timeoutConn{Conn: netConn, timeout: p.Timeout}
+
+	if err := writeRequest(conn, req); err != nil {
+		writeError(w, response.StatusBadGateway)
+		return
+	}
+
+	reader := response.NewReader(conn)
+	reader.StreamBody = true
+	resp, err := reader.ReadFinalResponse(req.RequestLine.Method)
+	if err != nil {
+		writeError(w, response.StatusBadGateway)
+		return
+	}
+
+	h := headers.NewHeaders()
+	copyHeaders(h, resp.Headers)
+	if _, chunked := resp.Headers.Get("transfer-encoding"); chunked {
+		// The body is relayed decoded, so a length sent alongside the
+		// chunked coding no longer describes anything.
+		h.Del("Content-Length")
+	}
+	h.Add("Via", resp.StatusLine.HttpVersion+" "+viaPseudonym)
+	if err := w.WriteStatusLine(resp.StatusLine.StatusCode); err != nil {
+		return
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return
+	}
+
+	if req.RequestLine.Method == "HEAD" {
+		return
+	}
+	if err := relayBody(w, resp, h); err != nil {
+		// The status line and headers are already out, so there is no
+		// error to send. Closing the connection is the only way to tell
+		// the client the body is incomplete, and keeps it from reading
+		// the next response as the rest of this one.
+		w.SetKeepAlive(false)
+	}
+}
+
+// relayBody copies the upstream body to the client as it arrives, then ends
+// a chunked body with the trailers h announced.
+func relayBody(w *response.Writer, resp *response.Response, h *headers.Headers) error {
+	buf := make([]byte, copyBufferSize)
+	for {
+		n, err := resp.BodyReader.Read(buf)
+		if n > 0 {
+			if _, werr := w.WriteBody(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !w.Chunked() {
+		return nil
+	}
+	if _, err := w.WriteChunkedBodyDone(); err != nil {
+		return err
+	}
+	if _, announced := h.Get("trailer"); announced {
+		return w.WriteTrailers(resp.Trailers)
+	}
+	return nil
+}
+
+// timeoutConn moves the deadline forward before every read and write, so
+// the upstream connection fails once it has been idle for timeout.
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Read(p []byte) (int, error) {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *timeoutConn) Write(p []byte) (int, error) {
+	if c.timeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Write(p)
+}
+
+// writeRequest sends req upstream. A body the server left in BodyReader is
+// streamed after the headers as the client sends it, framed the way the
+// client framed it: with the same Content-Length, or chunked and followed by
+// the client's trailers.
+func writeRequest(w io.Writer, req *request.Request) error {
+	if req.BodyReader == nil {
+		_, err := w.Write(outgoingRequest(req))
+		return err
+	}
+	h := forwardedHeaders(req)
+	isChunked, _ := chunked.IsChunked(req.Headers)
+	if isChunked {
+		// Codings applied before the chunked one still describe the body.
+		value, _ := req.Headers.Get("transfer-encoding")
+		h.Set("Transfer-Encoding", value)
+	} else if value, ok := req.Headers.Get("content-length"); ok {
+		// A repeated Content-Length was only accepted with matching values.
+		first, _, _ := strings.Cut(value, ",")
+		h.Set("Content-Length", strings.TrimSpace(first))
+	}
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "%s %s HTTP/1.1\r\n", req.RequestLine.Method, req.RequestLine.RequestTarget)
+	h.WriteTo(&head)
+	head.WriteString("\r\n")
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+
+	if !isChunked {
+		_, err := io.Copy(w, req.BodyReader)
+		return err
+	}
+	cw := chunked.NewWriter(w)
+	if _, err := io.Copy(cw, req.BodyReader); err != nil {
+		return err
+	}
+	return cw.Close(req.Trailers)
+}
+
+// outgoingRequest serializes req for the upstream: hop-by-hop fields are
+// replaced by the forwarding fields, and the already decoded body is framed
+// with Content-Length on a connection that closes after one response.
+func outgoingRequest(req *request.Request) []byte {
+	h := forwardedHeaders(req)
+	_, hasContentLength := req.Headers.Get("content-length")
+	_, hasTransferEncoding := req.Headers.Get("transfer-encoding")
+	if len(req.Body) > 0 || hasContentLength || hasTransferEncoding {
+		h.Set("Content-Length", strconv.Itoa(len(req.Body)))
+	}
+
+	out := &request.Request{
+		RequestLine: req.RequestLine,
+		Headers:     h,
+		Body:        req.Body,
+	}
+	out.RequestLine.HttpVersion = "1.1"
+	return out.Bytes()
+}
+
+// forwardedHeaders returns the fields to send upstream for req: its
+// end-to-end fields, the forwarding fields, and "Connection: close", since
+// each upstream connection carries one request.
+func forwardedHeaders(req *request.Request) *headers.Headers {
+	h := headers.NewHeaders()
+	copyHeaders(h, req.Headers)
+
+	clientIP := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		clientIP = host
+	}
+	if clientIP != "" {
+		if prior, ok := req.Headers.Get("x-forwarded-for"); ok {
+			clientIP = prior + ", " + clientIP
+		}
+		h.Set("X-Forwarded-For", clientIP)
+	}
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+	h.Set("X-Forwarded-Proto", proto)
+	h.Add("Via", req.RequestLine.HttpVersion+" "+viaPseudonym)
+	h.Set("Connection", "close")
+	return h
+}
+
+// copyHeaders adds every end-to-end field of src to dst, keeping order and
+// casing.
+func copyHeaders(dst, src *headers.Headers) {
+	skip := make(map[string]bool)
+	for _, name := range hopByHopHeaders {
+		skip[strings.ToLower(name)] = true
+	}
+	for _, value := range src.Values("connection") {
+		for _, token := range strings.Split(value, ",") {
+			skip[strings.ToLower(strings.TrimSpace(token))] = true
+		}
+	}
+	for name, value := range src.All() {
+		if !skip[strings.ToLower(name)] {
+			dst.Add(name, value)
+		}
+	}
+}
+
+// writeError answers with statusCode and its reason phrase as the body.
+func writeError(w *response.Writer, statusCode response.StatusCode) {
+	body := []byte(response.ReasonPhrase(statusCode) + "\n")
+	h := headers.NewHeaders()
+	h.Set("Content-Type", "text/plain")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	if err := w.WriteStatusLine(statusCode); err != nil {
+		return
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return
+	}
+	w.WriteBody(body)
+}
@@ -0,0 +1,343 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/client"
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/madhu1992blue/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstream is a stand-in backend. It answers each connection by handing
+// the parsed request to the test and writing the parts it is given, one
+// write per part, until the parts channel is closed.
+type upstream struct {
+	listener net.Listener
+	requests chan *request.Request
+	parts    chan string
+}
+
+func newUpstream(t *testing.T) *upstream {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	u := &upstream{
+		listener: listener,
+		requests: make(chan *request.Request, 1),
+		parts:    make(chan string),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			req, err := request.NewReader(conn).ReadRequest()
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			u.requests <- req
+			for part := range u.parts {
+				conn.Write([]byte(part))
+			}
+			conn.Close()
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return u
+}
+
+// send writes parts as the upstream response and ends it.
+func (u *upstream) send(parts ...string) {
+	go func() {
+		for _, part := range parts {
+			u.parts <- part
+		}
+		close(u.parts)
+	}()
+}
+
+func newProxyServer(t *testing.T, addr string) *server.Server {
+	t.Helper()
+	s, err := server.Serve(0, NewProxy(addr).ServeHTTP)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestProxyForwardsRequest(t *testing.T) {
+	u := newUpstream(t)
+	s := newProxyServer(t, u.listener.Addr().String())
+
+	c, err := client.Dial(s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	req := client.NewRequest("POST", "/submit?x=1", []byte("payload"))
+	req.Headers.Add("X-Custom", "kept")
+	req.Headers.Add("Connection", "keep-alive, X-Hop")
+	req.Headers.Add("X-Hop", "dropped")
+	req.Headers.Add("Keep-Alive", "timeout=5")
+	req.Headers.Add("Upgrade", "websocket")
+	req.Headers.Add("TE", "trailers")
+	req.Headers.Add("X-Forwarded-For", "203.0.113.7")
+	u.send("HTTP/1.1 201 Created\r\nContent-Length: 4\r\nKeep-Alive: timeout=5\r\nX-Backend: yes\r\n\r\ndone")
+
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+
+	// Test: Upstream sees end-to-end fields and the forwarding fields
+	got := <-u.requests
+	assert.Equal(t, "POST", got.RequestLine.Method)
+	assert.Equal(t, "/submit?x=1", got.RequestLine.RequestTarget)
+	assert.Equal(t, "payload", string(got.Body))
+	custom, _ := got.Headers.Get("X-Custom")
+	assert.Equal(t, "kept", custom)
+	for _, name := range []string{"X-Hop", "Keep-Alive", "Upgrade", "TE"} {
+		_, ok := got.Headers.Get(name)
+		assert.False(t, ok, name)
+	}
+	connection, _ := got.Headers.Get("Connection")
+	assert.Equal(t, "close", connection)
+	forwardedFor, _ := got.Headers.Get("X-Forwarded-For")
+	prior, clientIP, _ := strings.Cut(forwardedFor, ", ")
+	assert.Equal(t, "203.0.113.7", prior)
+	assert.True(t, net.ParseIP(clientIP).IsLoopback(), forwardedFor)
+	forwardedProto, _ := got.Headers.Get("X-Forwarded-Proto")
+	assert.Equal(t, "http", forwardedProto)
+	via, _ := got.Headers.Get("Via")
+	assert.Equal(t, "1.1 httpfromtcp", via)
+
+	// Test: Client sees the upstream response without its hop-by-hop fields
+	assert.Equal(t, response.StatusCreated, resp.StatusLine.StatusCode)
+	assert.Equal(t, "done", string(resp.Body))
+	backend, _ := resp.Headers.Get("X-Backend")
+	assert.Equal(t, "yes", backend)
+	_, ok := resp.Headers.Get("Keep-Alive")
+	assert.False(t, ok)
+	via, _ = resp.Headers.Get("Via")
+	assert.Equal(t, "1.1 httpfromtcp", via)
+}
+
+func TestProxyStreamsResponse(t *testing.T) {
+	u := newUpstream(t)
+	s := newProxyServer(t, u.listener.Addr().String())
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /report HTTP/1.1\r\nHost: proxy\r\n\r\n"))
+	require.NoError(t, err)
+	<-u.requests
+
+	u.parts <- "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nfirst \r\n"
+	reader := response.NewReader(conn)
+	reader.StreamBody = true
+	resp, err := reader.ReadResponse("GET")
+	require.NoError(t, err)
+
+	// Test: The first chunk reaches the client before the upstream finishes
+	buf := make([]byte, 6)
+	_, err = io.ReadFull(resp.BodyReader, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "first ", string(buf))
+
+	u.parts <- "6\r\nsecond\r\n0\r\n\r\n"
+	close(u.parts)
+	rest, err := io.ReadAll(resp.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(rest))
+	transferEncoding, _ := resp.Headers.Get("Transfer-Encoding")
+	assert.Equal(t, "chunked", transferEncoding)
+
+	// Test: The client connection stays usable after the chunked body
+	_, err = conn.Write([]byte("GET /next HTTP/1.1\r\nHost: proxy\r\n\r\n"))
+	require.NoError(t, err)
+	got := <-u.requests
+	assert.Equal(t, "/next", got.RequestLine.RequestTarget)
+}
+
+func TestProxyForwardsTrailers(t *testing.T) {
+	u := newUpstream(t)
+	s := newProxyServer(t, u.listener.Addr().String())
+
+	c, err := client.Dial(s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	u.send("HTTP/1.1 200 OK\r\nTrailer: X-Checksum\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"4\r\nbody\r\n0\r\nX-Checksum: abc\r\n\r\n")
+
+	// Test: Trailers announced upstream reach the client
+	resp, err := c.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	<-u.requests
+	assert.Equal(t, "body", string(resp.Body))
+	checksum, _ := resp.Trailers.Get("X-Checksum")
+	assert.Equal(t, "abc", checksum)
+}
+
+func TestProxyBadGateway(t *testing.T) {
+	// Test: Unreachable upstream
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	s := newProxyServer(t, addr)
+
+	c, err := client.Dial(s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	resp, err := c.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusBadGateway, resp.StatusLine.StatusCode)
+
+	// Test: Upstream sends garbage
+	u := newUpstream(t)
+	s = newProxyServer(t, u.listener.Addr().String())
+	c, err = client.Dial(s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	u.send("not http\r\n\r\n")
+	resp, err = c.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusBadGateway, resp.StatusLine.StatusCode)
+}
+
+// readUntilClose reads from conn until the proxy closes it, failing if that
+// takes longer than a few seconds.
+func readUntilClose(t *testing.T, conn net.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err, "connection was not closed")
+	return string(data)
+}
+
+func TestProxyUpstreamFailsMidBody(t *testing.T) {
+	u := newUpstream(t)
+	s := newProxyServer(t, u.listener.Addr().String())
+
+	// Test: A body cut short upstream closes the client connection
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	u.send("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\nhello")
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: proxy\r\n\r\n"))
+	require.NoError(t, err)
+	<-u.requests
+	got := readUntilClose(t, conn)
+	assert.Contains(t, got, "Content-Length: 100\r\n")
+	assert.True(t, strings.HasSuffix(got, "hello"))
+}
+
+func TestProxyTimeout(t *testing.T) {
+	u := newUpstream(t)
+	t.Cleanup(func() { close(u.parts) })
+	p := NewProxy(u.listener.Addr().String())
+	p.Timeout = 100 * time.Millisecond
+	s, err := server.Serve(0, p.ServeHTTP)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	// Test: An upstream that stops sending mid-body is given up on
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: proxy\r\n\r\n"))
+	require.NoError(t, err)
+	<-u.requests
+	u.parts <- "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nabc"
+	got := readUntilClose(t, conn)
+	assert.True(t, strings.HasSuffix(got, "abc"))
+}
+
+func newStreamingProxyServer(t *testing.T, addr string) *server.Server {
+	t.Helper()
+	s := server.NewServer(NewProxy(addr).ServeHTTP)
+	s.ParserOptions.StreamBody = true
+	require.NoError(t, s.Listen(0))
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestProxyStreamsRequestBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		framing string
+	}{
+		{
+			"content-length body",
+			"POST /upload HTTP/1.1\r\nContent-Length: 11\r\nConnection: close\r\n\r\nhello world",
+			"Content-Length: 11\r\n",
+		},
+		{
+			"chunked body with trailers",
+			"POST /upload HTTP/1.1\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n" +
+				"5\r\nhello\r\n6\r\n world\r\n0\r\nX-Sum: 1\r\n\r\n",
+			"Transfer-Encoding: chunked\r\n",
+		},
+	}
+	for _, tt := range tests {
+		// Test: A streamed body reaches the upstream with its framing
+		u := newUpstream(t)
+		s := newStreamingProxyServer(t, u.listener.Addr().String())
+		conn, err := net.Dial("tcp", s.Addr().String())
+		require.NoError(t, err, tt.name)
+		defer conn.Close()
+		u.send("HTTP/1.1 204 No Content\r\n\r\n")
+		_, err = conn.Write([]byte(tt.raw))
+		require.NoError(t, err, tt.name)
+		got := <-u.requests
+		assert.Equal(t, "hello world", string(got.Body), tt.name)
+		assert.Contains(t, string(got.Bytes()), tt.framing, tt.name)
+		if tt.framing == "Transfer-Encoding: chunked\r\n" {
+			sum, _ := got.Trailers.Get("X-Sum")
+			assert.Equal(t, "1", sum, tt.name)
+		}
+		assert.Contains(t, readUntilClose(t, conn), "204 No Content", tt.name)
+	}
+
+	// Test: The headers go upstream before the body has arrived
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	s := newStreamingProxyServer(t, listener.Addr().String())
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST /upload HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello"))
+	require.NoError(t, err)
+	upstreamConn, err := listener.Accept()
+	require.NoError(t, err)
+	defer upstreamConn.Close()
+	upstreamConn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	var received []byte
+	buf := make([]byte, 1024)
+	for !strings.HasSuffix(string(received), "\r\n\r\nhello") {
+		n, err := upstreamConn.Read(buf)
+		require.NoError(t, err, "upstream got %q", received)
+		received = append(received, buf[:n]...)
+	}
+	assert.Contains(t, string(received), "Content-Length: 11\r\n")
+}
+
+func TestForwardedProto(t *testing.T) {
+	req, err := request.RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+	require.NoError(t, err)
+
+	// Test: Plain request
+	assert.Contains(t, string(outgoingRequest(req)), "X-Forwarded-Proto: http\r\n")
+
+	// Test: Request that arrived over TLS
+	req.TLS = &tls.ConnectionState{}
+	assert.Contains(t, string(outgoingRequest(req)), "X-Forwarded-Proto: https\r\n")
+}
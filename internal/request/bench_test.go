@@ -0,0 +1,71 @@
+package request
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// benchRequest is a typical browser GET.
+const benchRequest = "GET /index.html?lang=en HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"User-Agent: Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0\r\n" +
+	"Accept: text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8\r\n" +
+	"Accept-Language: en-US,en;q=0.5\r\n" +
+	"Accept-Encoding: gzip, deflate, br\r\n" +
+	"Connection: keep-alive\r\n" +
+	"Cookie: session=0123456789abcdef\r\n" +
+	"\r\n"
+
+// benchPost is a small form submission with a body.
+const benchPost = "POST /submit HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"Content-Type: application/x-www-form-urlencoded\r\n" +
+	"Content-Length: 27\r\n" +
+	"\r\n" +
+	"name=gopher&language=golang"
+
+func BenchmarkRequestFromReader(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		data string
+	}{
+		{"GET", benchRequest},
+		{"POST", benchPost},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bench.data)))
+			r := strings.NewReader(bench.data)
+			for b.Loop() {
+				r.Reset(bench.data)
+				if _, err := RequestFromReader(r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkReaderPipelined reads many requests from one stream, as a server
+// does on a keep-alive connection.
+func BenchmarkReaderPipelined(b *testing.B) {
+	const perStream = 100
+	data := bytes.Repeat([]byte(benchRequest), perStream)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchRequest)))
+	r := bytes.NewReader(data)
+	reader := NewReader(r)
+	n := 0
+	for b.Loop() {
+		if n == perStream {
+			r.Reset(data)
+			reader = NewReader(r)
+			n = 0
+		}
+		if _, err := reader.ReadRequest(); err != nil {
+			b.Fatal(err)
+		}
+		n++
+	}
+}
@@ -0,0 +1,92 @@
+package request
+
+// Callbacks receives parse events as the state machine reaches them, so a
+// caller such as a proxy or an inspector can act on a request without
+// waiting for, or keeping, all of it. Every field is optional. An error
+// returned by a callback aborts the parse; the caller gets it back wrapped
+// in a *ParseError.
+type Callbacks struct {
+	// OnMethod, OnTarget and OnVersion are called once the request line has
+	// been parsed. The version is given without its "HTTP/" prefix.
+	OnMethod  func(method string) error
+	OnTarget  func(target string) error
+	OnVersion func(version string) error
+	// OnHeaderField and OnHeaderValue are called for each field line, of
+	// the headers and of any trailers after a chunked body. In ModeLenient
+	// an obs-fold continuation line calls OnHeaderValue again with the part
+	// to append to the value, after a single space.
+	OnHeaderField func(name string) error
+	OnHeaderValue func(value string) error
+	// OnHeadersComplete is called once the header section is complete and
+	// the body framing is known.
+	OnHeadersComplete func() error
+	// OnBodyChunk is called with each run of decoded body bytes. The slice
+	// is only valid during the call. When it is set the body is handed to
+	// the callback alone, and neither Body nor BodyReader receive it.
+	OnBodyChunk func(p []byte) error
+	// OnMessageComplete is called once the whole request, trailers
+	// included, has been parsed.
+	OnMessageComplete func() error
+}
+
+// onRequestLine reports the parts of the request line.
+func (c *Callbacks) onRequestLine(line RequestLine) error {
+	if c == nil {
+		return nil
+	}
+	if c.OnMethod != nil {
+		if err := c.OnMethod(line.Method); err != nil {
+			return err
+		}
+	}
+	if c.OnTarget != nil {
+		if err := c.OnTarget(line.RequestTarget); err != nil {
+			return err
+		}
+	}
+	if c.OnVersion != nil {
+		return c.OnVersion(line.HttpVersion)
+	}
+	return nil
+}
+
+// onField reports a field line.
+func (c *Callbacks) onField(name, value string) error {
+	if c == nil {
+		return nil
+	}
+	if c.OnHeaderField != nil {
+		if err := c.OnHeaderField(name); err != nil {
+			return err
+		}
+	}
+	return c.onValue(value)
+}
+
+// onValue reports a field value, or the continuation of one.
+func (c *Callbacks) onValue(value string) error {
+	if c == nil || c.OnHeaderValue == nil {
+		return nil
+	}
+	return c.OnHeaderValue(value)
+}
+
+func (c *Callbacks) onHeadersComplete() error {
+	if c == nil || c.OnHeadersComplete == nil {
+		return nil
+	}
+	return c.OnHeadersComplete()
+}
+
+func (c *Callbacks) onMessageComplete() error {
+	if c == nil || c.OnMessageComplete == nil {
+		return nil
+	}
+	return c.OnMessageComplete()
+}
+
+// takesBody reports whether body bytes go to OnBodyChunk rather than being
+// stored.
+func (c *Callbacks) takesBody() bool {
+	return c != nil && c.OnBodyChunk != nil
+}
@@ -0,0 +1,156 @@
+package request
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordEvents returns callbacks that log every event into events.
+func recordEvents(events *[]string) *Callbacks {
+	record := func(kind string) func(string) error {
+		return func(s string) error {
+			*events = append(*events, kind+" "+s)
+			return nil
+		}
+	}
+	return &Callbacks{
+		OnMethod:      record("method"),
+		OnTarget:      record("target"),
+		OnVersion:     record("version"),
+		OnHeaderField: record("field"),
+		OnHeaderValue: record("value"),
+		OnHeadersComplete: func() error {
+			*events = append(*events, "headers complete")
+			return nil
+		},
+		OnBodyChunk: func(p []byte) error {
+			*events = append(*events, "body "+string(p))
+			return nil
+		},
+		OnMessageComplete: func() error {
+			*events = append(*events, "message complete")
+			return nil
+		},
+	}
+}
+
+func TestCallbacks(t *testing.T) {
+	// Test: Events arrive in order for a chunked request with trailers
+	var events []string
+	reader := NewReader(&chunkReader{
+		data:            "POST /upload HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\nX-Sum: 11\r\n\r\n",
+		numBytesPerRead: 64,
+	})
+	reader.Options.Callbacks = recordEvents(&events)
+	r, err := reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"method POST",
+		"target /upload",
+		"version 1.1",
+		"field Host",
+		"value localhost",
+		"field Transfer-Encoding",
+		"value chunked",
+		"headers complete",
+		"body hello",
+		"body  world",
+		"field X-Sum",
+		"value 11",
+		"message complete",
+	}, events)
+
+	// Test: The body goes to OnBodyChunk instead of Body
+	assert.Empty(t, r.Body)
+	sum, _ := r.Trailers.Get("X-Sum")
+	assert.Equal(t, "11", sum)
+
+	// Test: Small reads split the body into more chunks, not other events
+	events = nil
+	reader = NewReader(&chunkReader{
+		data:            "PUT /file HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world",
+		numBytesPerRead: 3,
+	})
+	reader.Options.Callbacks = recordEvents(&events)
+	_, err = reader.ReadRequest()
+	require.NoError(t, err)
+	var body strings.Builder
+	for _, e := range events {
+		if chunk, ok := strings.CutPrefix(e, "body "); ok {
+			body.WriteString(chunk)
+		}
+	}
+	assert.Equal(t, "hello world", body.String())
+	assert.Equal(t, "message complete", events[len(events)-1])
+
+	// Test: OnMessageComplete fires once for a request without a body
+	events = nil
+	p := NewParser()
+	p.Options.Callbacks = recordEvents(&events)
+	p.Reset()
+	_, err = p.Feed([]byte("GET / HTTP/1.1\r\nHost: a\r\n\r\n"))
+	require.NoError(t, err)
+	_, err = p.Feed([]byte("GET /next HTTP/1.1\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"method GET", "target /", "version 1.1", "field Host", "value a", "headers complete", "message complete"}, events)
+
+	// Test: Obs-fold continuations are reported as more of the value
+	events = nil
+	reader = NewReader(strings.NewReader("GET / HTTP/1.1\r\nX-Long: first\r\n second\r\n\r\n"))
+	reader.Options.Mode = ModeLenient
+	reader.Options.Callbacks = recordEvents(&events)
+	_, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"field X-Long", "value first", "value second"}, events[3:6])
+}
+
+func TestCallbackErrors(t *testing.T) {
+	errRejected := errors.New("rejected")
+
+	// Test: An error from a header callback aborts the parse
+	var fields []string
+	reader := NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: a\r\nX-Secret: 1\r\nAccept: */*\r\n\r\n"))
+	reader.Options.Callbacks = &Callbacks{
+		OnHeaderField: func(name string) error {
+			fields = append(fields, name)
+			if strings.EqualFold(name, "x-secret") {
+				return errRejected
+			}
+			return nil
+		},
+	}
+	_, err := reader.ReadRequest()
+	assert.ErrorIs(t, err, errRejected)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 25, parseErr.Offset)
+	assert.Equal(t, []string{"Host", "X-Secret"}, fields)
+
+	// Test: An error from OnBodyChunk stops the body
+	reader = NewReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nContent-Length: 10\r\n\r\n0123456789",
+		numBytesPerRead: 4,
+	})
+	seen := 0
+	reader.Options.Callbacks = &Callbacks{
+		OnBodyChunk: func(p []byte) error {
+			seen += len(p)
+			return errRejected
+		},
+	}
+	_, err = reader.ReadRequest()
+	assert.ErrorIs(t, err, errRejected)
+	assert.Less(t, seen, 10)
+
+	// Test: An error from OnMessageComplete is returned
+	reader = NewReader(strings.NewReader("GET / HTTP/1.1\r\n\r\n"))
+	reader.Options.Callbacks = &Callbacks{
+		OnMessageComplete: func() error { return errRejected },
+	}
+	_, err = reader.ReadRequest()
+	assert.ErrorIs(t, err, errRejected)
+}
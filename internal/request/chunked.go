@@ -0,0 +1,88 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/chunked"
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+var (
+	// ErrInvalidChunkSize is returned when a chunk-size line does not start
+	// with a valid hexadecimal size.
+	ErrInvalidChunkSize = chunked.ErrInvalidSize
+	// ErrMissingChunkCRLF is returned when chunk data is not followed by CRLF.
+	ErrMissingChunkCRLF = chunked.ErrMissingCRLF
+	// ErrContentLengthWithChunked is returned when a request declares both
+	// Content-Length and a chunked Transfer-Encoding.
+	ErrContentLengthWithChunked = errors.New("content-length not allowed with chunked transfer-encoding")
+	// ErrUnsupportedTransferEncoding is returned when the final transfer
+	// coding is not chunked, which leaves the body length undefined.
+	ErrUnsupportedTransferEncoding = errors.New("unsupported transfer-encoding")
+)
+
+// isChunked reports whether the Transfer-Encoding header ends in chunked.
+// A request with any other final coding has no way to delimit its body.
+func isChunked(h *headers.Headers) (bool, error) {
+	isChunked, present := chunked.IsChunked(h)
+	if present && !isChunked {
+		value, _ := h.Get("transfer-encoding")
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedTransferEncoding, value)
+	}
+	return isChunked, nil
+}
+
+// parseChunked handles a single step of a chunked body: a chunk-size line,
+// some chunk data, the CRLF after the data, or one trailer field.
+func (r *Request) parseChunked(data []byte) (int, error) {
+	switch r.ParserState {
+	case requestStateParsingChunkSize:
+		if r.mode == ModeStrict {
+			if err := checkLineEnding(data); err != nil {
+				return 0, err
+			}
+		}
+		size, n, err := chunked.ParseSize(data)
+		if err != nil || n == 0 {
+			return 0, err
+		}
+		if exceeds(r.bodyRead+size, r.maxBodyBytes()) {
+			return 0, errBodyTooLarge(r.maxBodyBytes())
+		}
+		if size == 0 {
+			r.ParserState = requestStateParsingTrailers
+		} else {
+			r.chunkRemaining = size
+			r.ParserState = requestStateParsingChunkData
+		}
+		return n, nil
+	case requestStateParsingChunkData:
+		n := min(r.chunkRemaining, len(data))
+		if err := r.appendBody(data[:n]); err != nil {
+			return 0, err
+		}
+		r.chunkRemaining -= n
+		if r.chunkRemaining == 0 {
+			r.ParserState = requestStateParsingChunkDataEnd
+		}
+		return n, nil
+	case requestStateParsingChunkDataEnd:
+		n, err := chunked.ParseDataEnd(data)
+		if err != nil || n == 0 {
+			return 0, err
+		}
+		r.ParserState = requestStateParsingChunkSize
+		return n, nil
+	case requestStateParsingTrailers:
+		n, done, err := r.parseField(r.Trailers, data)
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			r.ParserState = requestStateDone
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown parser state: %d", r.ParserState)
+}
@@ -0,0 +1,112 @@
+package request
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkedBodyParse(t *testing.T) {
+	// Test: Chunked body with extensions and trailers
+	reader := &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5;name=value\r\n" +
+			"hello\r\n" +
+			"7\r\n" +
+			", world\r\n" +
+			"0\r\n" +
+			"X-Checksum: abc123\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "hello, world", string(r.Body))
+	checksum, ok := r.Trailers.Get("X-Checksum")
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", checksum)
+
+	// Test: Upper-case hex size, no trailers
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Transfer-Encoding: gzip, Chunked\r\n" +
+			"\r\n" +
+			"A\r\n" +
+			"0123456789\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 1,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(r.Body))
+	assert.Empty(t, r.Trailers)
+
+	// Test: Invalid hex size
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"zz\r\n" +
+			"hello\r\n" +
+			"0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	assert.ErrorIs(t, err, ErrInvalidChunkSize)
+
+	// Test: Chunk data not followed by CRLF
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\n" +
+			"hello\r\n" +
+			"0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	assert.ErrorIs(t, err, ErrMissingChunkCRLF)
+
+	// Test: Content-Length together with chunked
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Content-Length: 5\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\n" +
+			"hello\r\n" +
+			"0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	assert.ErrorIs(t, err, ErrContentLengthWithChunked)
+
+	// Test: Final coding is not chunked
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Transfer-Encoding: chunked, gzip\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	assert.ErrorIs(t, err, ErrUnsupportedTransferEncoding)
+
+	// Test: Stream ends before the terminating chunk
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\n" +
+			"hello\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,116 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+var (
+	ErrInvalidRequestLine       = errors.New("invalid request line")
+	ErrInvalidMethod            = errors.New("invalid method")
+	ErrInvalidVersion           = errors.New("invalid HTTP version")
+	ErrUnsupportedVersion       = errors.New("unsupported HTTP version")
+	ErrInvalidContentLength     = errors.New("invalid content-length")
+	ErrConflictingContentLength = errors.New("conflicting content-length values")
+	// ErrBodyTooShort is returned when the stream ends before Content-Length
+	// bytes of body have been read.
+	ErrBodyTooShort = errors.New("body shorter than content-length")
+)
+
+// ParseError is returned for every request that fails to parse, as opposed
+// to failing to be read. It wraps one of the package's Err* values, or one
+// from the headers package, so it works with errors.Is and errors.As.
+type ParseError struct {
+	// Offset is the byte offset, from the start of the request, of the line
+	// or chunk that failed to parse.
+	Offset int
+	// State is the parser state the failure happened in.
+	State ParserState
+	// StatusCode is the response status a server should answer with.
+	StatusCode int
+	Err        error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("error parsing %s at byte %d: %v", e.State.name(), e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func newParseError(offset int, state ParserState, err error) *ParseError {
+	return &ParseError{
+		Offset:     offset,
+		State:      state,
+		StatusCode: statusFor(err),
+		Err:        err,
+	}
+}
+
+// statusFor suggests the response status for a parse failure. Anything not
+// listed is a malformed request. That includes ErrInvalidMethod and
+// ErrTargetFormNotAllowed: 405 is left to the router, since a 405 must list
+// the methods the resource allows in an Allow header, and the parser knows
+// no resources.
+func statusFor(err error) int {
+	var limitErr *LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		return limitErr.StatusCode
+	case errors.Is(err, ErrUnsupportedVersion):
+		return 505
+	case errors.Is(err, ErrUnsupportedTransferEncoding):
+		return 501
+	}
+	return 400
+}
+
+// name describes the state for error messages. It is not a String method
+// because Request embeds ParserState and would print as its state.
+func (s ParserState) name() string {
+	switch s {
+	case requestStateInitialized:
+		return "request line"
+	case requestStateParsingHeaders:
+		return "headers"
+	case requestStateParsingBody:
+		return "body"
+	case requestStateParsingChunkSize:
+		return "chunk size"
+	case requestStateParsingChunkData, requestStateParsingChunkDataEnd:
+		return "chunk data"
+	case requestStateParsingTrailers:
+		return "trailers"
+	case requestStateDone:
+		return "done"
+	}
+	return fmt.Sprintf("ParserState(%d)", int(s))
+}
+
+// Errors from the headers package, re-exported so callers can match every
+// parse failure against this package.
+var (
+	ErrInvalidFieldLine = headers.ErrInvalidFieldLine
+	ErrInvalidFieldName = headers.ErrInvalidFieldName
+	ErrSpaceBeforeColon = headers.ErrSpaceBeforeColon
+)
+
+// eofError describes why hitting the end of the stream in the current state
+// leaves the request incomplete.
+func (r *Request) eofError() error {
+	var err error
+	switch r.ParserState {
+	case requestStateParsingBody:
+		err = fmt.Errorf("%w: got %d of %d bytes", ErrBodyTooShort, r.bodyRead, r.contentLength)
+	case requestStateParsingChunkSize, requestStateParsingChunkData,
+		requestStateParsingChunkDataEnd, requestStateParsingTrailers:
+		err = fmt.Errorf("%w: chunked body ended early", io.ErrUnexpectedEOF)
+	default:
+		err = io.ErrUnexpectedEOF
+	}
+	return newParseError(r.offset, r.ParserState, err)
+}
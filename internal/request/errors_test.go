@@ -0,0 +1,68 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		err        error
+		state      ParserState
+		offset     int
+		statusCode int
+	}{
+		{"bad request line", "GET /\r\n\r\n", ErrInvalidRequestLine, requestStateInitialized, 0, 400},
+		{"bad method", "get / HTTP/1.1\r\n\r\n", ErrInvalidMethod, requestStateInitialized, 0, 400},
+		{"bad version", "GET / HTTX/1.1\r\n\r\n", ErrInvalidVersion, requestStateInitialized, 0, 400},
+		{"unsupported version", "GET / HTTP/2.0\r\n\r\n", ErrUnsupportedVersion, requestStateInitialized, 0, 505},
+		{"bad target", "GET coffee HTTP/1.1\r\n\r\n", ErrInvalidTarget, requestStateInitialized, 0, 400},
+		{"asterisk for GET", "GET * HTTP/1.1\r\n\r\n", ErrTargetFormNotAllowed, requestStateInitialized, 0, 400},
+		{"origin form for CONNECT", "CONNECT /path HTTP/1.1\r\n\r\n", ErrTargetFormNotAllowed, requestStateInitialized, 0, 400},
+		{"bad header line", "GET / HTTP/1.1\r\nHost: a\r\nBroken\r\n\r\n", ErrInvalidFieldLine, requestStateParsingHeaders, 25, 400},
+		{"bad header name", "GET / HTTP/1.1\r\nH@st: a\r\n\r\n", ErrInvalidFieldName, requestStateParsingHeaders, 16, 400},
+		{"space before colon", "GET / HTTP/1.1\r\nHost : a\r\n\r\n", ErrSpaceBeforeColon, requestStateParsingHeaders, 16, 400},
+		{"bad content length", "POST / HTTP/1.1\r\nContent-Length: x\r\n\r\n", ErrInvalidContentLength, requestStateParsingHeaders, 36, 400},
+		{"bad chunk size", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n", ErrInvalidChunkSize, requestStateParsingChunkSize, 47, 400},
+		{"unknown coding", "POST / HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n", ErrUnsupportedTransferEncoding, requestStateParsingHeaders, 42, 501},
+		{"short body", "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nab", ErrBodyTooShort, requestStateParsingBody, 40, 400},
+		{"truncated headers", "GET / HTTP/1.1\r\nHost: a", io.ErrUnexpectedEOF, requestStateParsingHeaders, 16, 400},
+	}
+	for _, tt := range tests {
+		_, err := RequestFromReader(&chunkReader{data: tt.data, numBytesPerRead: 3})
+		require.Error(t, err, tt.name)
+		assert.ErrorIs(t, err, tt.err, tt.name)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), tt.name)
+		assert.Equal(t, tt.state, parseErr.State, tt.name)
+		assert.Equal(t, tt.offset, parseErr.Offset, tt.name)
+		assert.Equal(t, tt.statusCode, parseErr.StatusCode, tt.name)
+	}
+
+	// Test: Limit errors keep their status code
+	reader := NewReader(&chunkReader{data: "GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\n\r\n", numBytesPerRead: 3})
+	reader.Options.Limits.MaxHeaderCount = 1
+	_, err := reader.ReadRequest()
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 431, parseErr.StatusCode)
+	var limitErr *LimitError
+	assert.True(t, errors.As(err, &limitErr))
+
+	// Test: Read errors are not parse errors
+	_, err = RequestFromReader(&failingReader{})
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &parseErr))
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,111 @@
+package request
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+// Leniency names a quirk that ModeLenient accepted in a request.
+type Leniency string
+
+const (
+	LeniencyBareLF                Leniency = "bare LF line ending"
+	LeniencyRequestLineWhitespace Leniency = "extra whitespace in request line"
+	LeniencyObsFold               Leniency = "obs-fold continuation line"
+)
+
+// addLeniency records that a quirk was accepted, once per request.
+func (r *Request) addLeniency(l Leniency) {
+	if !slices.Contains(r.Leniencies, l) {
+		r.Leniencies = append(r.Leniencies, l)
+	}
+}
+
+// atLineStart reports whether the parser expects a line next, rather than
+// body data.
+func (r *Request) atLineStart() bool {
+	switch r.ParserState {
+	case requestStateInitialized,
+		requestStateParsingHeaders,
+		requestStateParsingChunkSize,
+		requestStateParsingChunkDataEnd,
+		requestStateParsingTrailers:
+		return true
+	}
+	return false
+}
+
+// parseLenientLine rewrites the next line into the form the parser expects,
+// CRLF ending included, and parses that in place of the original. Every line
+// state consumes its whole line, so on success the original line is
+// consumed in full.
+func (r *Request) parseLenientLine(data []byte) (int, error) {
+	i := bytes.IndexByte(data, '\n')
+	if i == -1 {
+		// Not a whole line yet, which the parser may still need to see to
+		// enforce its limits.
+		return r.parseStep(data)
+	}
+	line := bytes.TrimSuffix(data[:i], []byte("\r"))
+	if len(line) == i {
+		r.addLeniency(LeniencyBareLF)
+	}
+
+	switch r.ParserState {
+	case requestStateInitialized:
+		normalized := bytes.Join(bytes.FieldsFunc(line, isSpaceOrTab), []byte(" "))
+		if !bytes.Equal(normalized, line) {
+			r.addLeniency(LeniencyRequestLineWhitespace)
+			line = normalized
+		}
+	case requestStateParsingHeaders, requestStateParsingTrailers:
+		if len(line) > 0 && isSpaceOrTab(rune(line[0])) {
+			h := r.Headers
+			if r.ParserState == requestStateParsingTrailers {
+				h = r.Trailers
+			}
+			folded, err := r.foldLine(h, line, i+1)
+			if err != nil {
+				return 0, err
+			}
+			if folded {
+				return i + 1, nil
+			}
+		}
+	}
+
+	fixed := make([]byte, 0, len(line)+2)
+	fixed = append(fixed, line...)
+	fixed = append(fixed, "\r\n"...)
+	n, err := r.parseStep(fixed)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	return i + 1, nil
+}
+
+// foldLine joins an obs-fold continuation line, n bytes long in the
+// request, to the field above it. It reports false when there is no field
+// to continue.
+func (r *Request) foldLine(h *headers.Headers, line []byte, n int) (bool, error) {
+	r.headerBytes += n
+	if exceeds(r.headerBytes, r.limits.MaxHeaderBytes) {
+		return false, errHeadersTooLarge(r.limits.MaxHeaderBytes)
+	}
+	value := string(bytes.TrimSpace(line))
+	if !h.AppendToLast(value) {
+		r.headerBytes -= n
+		return false, nil
+	}
+	r.addLeniency(LeniencyObsFold)
+	if err := r.callbacks.onValue(value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func isSpaceOrTab(c rune) bool {
+	return c == ' ' || c == '\t'
+}
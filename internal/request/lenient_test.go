@@ -0,0 +1,74 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLenientMode(t *testing.T) {
+	// Test: Bare LF line endings throughout
+	r, err := readWithMode("POST /upload HTTP/1.1\nHost: device\nTransfer-Encoding: chunked\n\n5\nhello\n0\nX-Sum: 1\n\n", ModeLenient)
+	require.NoError(t, err)
+	assert.Equal(t, "/upload", r.RequestLine.RequestTarget)
+	host, _ := r.Headers.Get("Host")
+	assert.Equal(t, "device", host)
+	assert.Equal(t, "hello", string(r.Body))
+	sum, _ := r.Trailers.Get("X-Sum")
+	assert.Equal(t, "1", sum)
+	assert.Equal(t, []Leniency{LeniencyBareLF}, r.Leniencies)
+
+	// Test: Runs of spaces and tabs in the request line
+	r, err = readWithMode("GET   /path\tHTTP/1.1 \r\nHost: device\r\n\r\n", ModeLenient)
+	require.NoError(t, err)
+	assert.Equal(t, "GET", r.RequestLine.Method)
+	assert.Equal(t, "/path", r.RequestLine.RequestTarget)
+	assert.Equal(t, "1.1", r.RequestLine.HttpVersion)
+	assert.Equal(t, []Leniency{LeniencyRequestLineWhitespace}, r.Leniencies)
+
+	// Test: Obs-fold lines are joined with a single space
+	r, err = readWithMode("GET / HTTP/1.1\r\nX-Long: first\r\n  second\r\n\tthird\r\nHost: device\r\n\r\n", ModeLenient)
+	require.NoError(t, err)
+	long, _ := r.Headers.Get("X-Long")
+	assert.Equal(t, "first second third", long)
+	assert.Equal(t, 2, r.Headers.Len())
+	assert.Equal(t, []Leniency{LeniencyObsFold}, r.Leniencies)
+
+	// Test: Several quirks are each recorded once, in order
+	r, err = readWithMode("GET  / HTTP/1.1\nX-A: 1\n 2\nX-B: 3\n 4\n\n", ModeLenient)
+	require.NoError(t, err)
+	assert.Equal(t, []Leniency{LeniencyBareLF, LeniencyRequestLineWhitespace, LeniencyObsFold}, r.Leniencies)
+
+	// Test: Well-formed requests record nothing
+	r, err = readWithMode("POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello", ModeLenient)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(r.Body))
+	assert.Empty(t, r.Leniencies)
+
+	// Test: Folded lines still count against the header limit
+	reader := NewReader(&chunkReader{data: "GET / HTTP/1.1\r\nX-A: 1\r\n 2222222222\r\n 3333333333\r\n\r\n", numBytesPerRead: 3})
+	reader.Options.Mode = ModeLenient
+	reader.Options.Limits.MaxHeaderBytes = 20
+	_, err = reader.ReadRequest()
+	assert.ErrorIs(t, err, ErrHeadersTooLarge)
+
+	// Test: Other errors are still reported
+	_, err = readWithMode("GET / HTTP/1.1\nHost : device\n\n", ModeLenient)
+	assert.ErrorIs(t, err, ErrSpaceBeforeColon)
+}
+
+func TestRequestLineSpacing(t *testing.T) {
+	// Test: Default and strict modes want single spaces in the request line
+	for _, mode := range []ParseMode{ModeDefault, ModeStrict} {
+		for _, data := range []string{
+			"GET  / HTTP/1.1\r\n\r\n",
+			"GET /\tHTTP/1.1\r\n\r\n",
+			"GET / HTTP/1.1 \r\n\r\n",
+			" GET / HTTP/1.1\r\n\r\n",
+		} {
+			_, err := readWithMode(data, mode)
+			assert.ErrorIs(t, err, ErrInvalidRequestLine, data)
+		}
+	}
+}
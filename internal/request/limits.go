@@ -0,0 +1,108 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits bounds how much of a request the parser accepts, so a client cannot
+// exhaust memory by never ending a line or a body. A zero field means no
+// limit.
+type Limits struct {
+	// MaxRequestLineBytes bounds the request line, excluding its CRLF.
+	MaxRequestLineBytes int
+	// MaxHeaderBytes bounds the whole header section, including the CRLFs
+	// and any trailer section after a chunked body.
+	MaxHeaderBytes int
+	// MaxHeaderCount bounds the number of header and trailer field lines.
+	MaxHeaderCount int
+	// MaxBodyBytes bounds the decoded body kept in Request.Body.
+	MaxBodyBytes int
+	// MaxStreamBodyBytes bounds the decoded body of a request read with
+	// StreamBody, which is not held in memory. It is zero, no limit, by
+	// default.
+	MaxStreamBodyBytes int
+}
+
+// DefaultLimits are the limits used by RequestFromReader and new Readers.
+var DefaultLimits = Limits{
+	MaxRequestLineBytes: 8 << 10,
+	MaxHeaderBytes:      64 << 10,
+	MaxHeaderCount:      100,
+	MaxBodyBytes:        10 << 20,
+}
+
+// ParserOptions configures how a Reader parses requests.
+type ParserOptions struct {
+	Limits Limits
+	// StreamBody makes ReadRequest return as soon as the headers are parsed,
+	// leaving the body to be read through Request.BodyReader. A streamed
+	// body is bounded by Limits.MaxStreamBodyBytes rather than MaxBodyBytes,
+	// so that large uploads need no change to the limits.
+	StreamBody bool
+	// Mode chooses how forgiving the parser is of malformed requests.
+	Mode ParseMode
+	// Callbacks, if set, is told about each part of a request as it is
+	// parsed.
+	Callbacks *Callbacks
+}
+
+// DefaultParserOptions returns the options used by RequestFromReader.
+func DefaultParserOptions() ParserOptions {
+	return ParserOptions{
+		Limits: DefaultLimits,
+	}
+}
+
+var (
+	ErrRequestLineTooLong = errors.New("request line too long")
+	ErrHeadersTooLarge    = errors.New("header section too large")
+	ErrTooManyHeaders     = errors.New("too many header fields")
+	ErrBodyTooLarge       = errors.New("body too large")
+)
+
+// LimitError is returned when a request exceeds one of its Limits. It wraps
+// one of the Err*TooLong/TooLarge/TooMany errors and carries the status code
+// a server should answer with.
+type LimitError struct {
+	Err        error
+	Limit      int
+	StatusCode int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: limit is %d", e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+func errRequestLineTooLong(limit int) error {
+	return &LimitError{Err: ErrRequestLineTooLong, Limit: limit, StatusCode: 414}
+}
+
+func errHeadersTooLarge(limit int) error {
+	return &LimitError{Err: ErrHeadersTooLarge, Limit: limit, StatusCode: 431}
+}
+
+func errTooManyHeaders(limit int) error {
+	return &LimitError{Err: ErrTooManyHeaders, Limit: limit, StatusCode: 431}
+}
+
+func errBodyTooLarge(limit int) error {
+	return &LimitError{Err: ErrBodyTooLarge, Limit: limit, StatusCode: 413}
+}
+
+// maxBodyBytes returns the limit that applies to the body of r.
+func (r *Request) maxBodyBytes() int {
+	if r.streamBody {
+		return r.limits.MaxStreamBodyBytes
+	}
+	return r.limits.MaxBodyBytes
+}
+
+// exceeds reports whether n is over limit, treating a zero limit as none.
+func exceeds(n, limit int) bool {
+	return limit > 0 && n > limit
+}
@@ -0,0 +1,92 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readWithLimits(data string, limits Limits) (*Request, error) {
+	reader := NewReader(&chunkReader{data: data, numBytesPerRead: 5})
+	reader.Options.Limits = limits
+	return reader.ReadRequest()
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{
+		MaxRequestLineBytes: 32,
+		MaxHeaderBytes:      64,
+		MaxHeaderCount:      3,
+		MaxBodyBytes:        10,
+	}
+
+	// Test: Request inside every limit
+	r, err := readWithLimits("POST /submit HTTP/1.1\r\nHost: localhost\r\nContent-Length: 10\r\n\r\n0123456789", limits)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(r.Body))
+
+	// Test: Request line without a CRLF
+	_, err = readWithLimits("GET /"+strings.Repeat("a", 100), limits)
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+	var limitErr *LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 414, limitErr.StatusCode)
+	assert.Equal(t, 32, limitErr.Limit)
+
+	// Test: Complete but long request line
+	_, err = readWithLimits("GET /"+strings.Repeat("a", 30)+" HTTP/1.1\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	// Test: Header line without a CRLF
+	_, err = readWithLimits("GET / HTTP/1.1\r\nX-Long: "+strings.Repeat("a", 100), limits)
+	assert.ErrorIs(t, err, ErrHeadersTooLarge)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 431, limitErr.StatusCode)
+
+	// Test: Too many header fields
+	_, err = readWithLimits("GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\nD: 4\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrTooManyHeaders)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 431, limitErr.StatusCode)
+
+	// Test: Declared body over the limit is rejected before it is read
+	_, err = readWithLimits("POST / HTTP/1.1\r\nContent-Length: 11\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 413, limitErr.StatusCode)
+
+	// Test: Chunked body growing over the limit
+	_, err = readWithLimits("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nhello \r\n6\r\nworld!\r\n0\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: Streamed bodies have their own limit, none by default
+	reader := NewReader(&chunkReader{data: "POST / HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world", numBytesPerRead: 5})
+	reader.Options.Limits = limits
+	reader.Options.StreamBody = true
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	body, err := io.ReadAll(r.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(body))
+	reader = NewReader(&chunkReader{data: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n6\r\nhello \r\n6\r\nworld!\r\n0\r\n\r\n", numBytesPerRead: 5})
+	reader.Options.Limits = limits
+	reader.Options.Limits.MaxStreamBodyBytes = 8
+	reader.Options.StreamBody = true
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	_, err = io.ReadAll(r.BodyReader)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: Trailers count toward the header limits
+	_, err = readWithLimits("POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrTooManyHeaders)
+
+	// Test: Zero limits disable the checks
+	r, err = readWithLimits("GET /"+strings.Repeat("a", 100)+" HTTP/1.1\r\n\r\n", Limits{})
+	require.NoError(t, err)
+	assert.Len(t, r.RequestLine.RequestTarget, 101)
+}
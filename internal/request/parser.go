@@ -0,0 +1,79 @@
+package request
+
+// Parser parses one request at a time from bytes pushed into it, for callers
+// that read from the network themselves, such as an event loop, or that
+// replay captured traffic. It runs the same state machine as Reader.
+type Parser struct {
+	// Options applies from the next Reset. Bodies are always collected in
+	// Request.Body; StreamBody is ignored.
+	Options ParserOptions
+
+	req *Request
+	// buf holds fed bytes the state machine could not use yet, such as the
+	// start of a line whose end has not arrived.
+	buf []byte
+	err error
+}
+
+// NewParser returns a parser with the default options, ready for the first
+// request. The zero Parser works too, without any limits.
+func NewParser() *Parser {
+	p := &Parser{Options: DefaultParserOptions()}
+	p.Reset()
+	return p
+}
+
+// Feed pushes the next bytes of the request into the parser. Bytes that do
+// not complete a line or other unit yet are kept for the next call, so
+// consumed equals len(data) until the request is done; after that it tells
+// where the request ended, and data[consumed:] belongs to the next one.
+// Once Feed has failed, every later call returns the same error until
+// Reset.
+func (p *Parser) Feed(data []byte) (consumed int, err error) {
+	if p.req == nil {
+		p.Reset()
+	}
+	if p.err != nil {
+		return 0, p.err
+	}
+	if p.Done() {
+		return 0, nil
+	}
+	input := data
+	buffered := len(p.buf)
+	if buffered > 0 {
+		p.buf = append(p.buf, data...)
+		input = p.buf
+	}
+	n, err := p.req.parse(input)
+	if err != nil {
+		p.err = err
+		return 0, err
+	}
+	if p.Done() {
+		p.buf = p.buf[:0]
+		return max(n-buffered, 0), nil
+	}
+	p.buf = append(p.buf[:0], input[n:]...)
+	return len(data), nil
+}
+
+// Done reports whether a whole request has been parsed.
+func (p *Parser) Done() bool {
+	return p.req != nil && p.req.ParserState == requestStateDone
+}
+
+// Request returns the request being parsed. It is only complete once Done
+// reports true.
+func (p *Parser) Request() *Request {
+	return p.req
+}
+
+// Reset readies the parser for a new request, keeping its buffer for reuse.
+func (p *Parser) Reset() {
+	opts := p.Options
+	opts.StreamBody = false
+	p.req = newRequest(opts)
+	p.buf = p.buf[:0]
+	p.err = nil
+}
@@ -0,0 +1,95 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserFeed(t *testing.T) {
+	raw := "POST /submit HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello"
+
+	// Test: One byte at a time
+	p := NewParser()
+	for i := range len(raw) {
+		assert.False(t, p.Done())
+		n, err := p.Feed([]byte{raw[i]})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+	require.True(t, p.Done())
+	r := p.Request()
+	assert.Equal(t, "POST", r.RequestLine.Method)
+	assert.Equal(t, "/submit", r.RequestLine.RequestTarget)
+	host, _ := r.Headers.Get("Host")
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, "hello", string(r.Body))
+
+	// Test: Feeding a done parser consumes nothing
+	n, err := p.Feed([]byte("GET"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Test: Pipelined requests in one buffer
+	second := "GET /next HTTP/1.1\r\n\r\n"
+	p.Reset()
+	data := []byte(raw + second)
+	n, err = p.Feed(data)
+	require.NoError(t, err)
+	require.True(t, p.Done())
+	assert.Equal(t, len(raw), n)
+	p.Reset()
+	n, err = p.Feed(data[n:])
+	require.NoError(t, err)
+	assert.Equal(t, len(second), n)
+	require.True(t, p.Done())
+	assert.Equal(t, "/next", p.Request().RequestLine.RequestTarget)
+
+	// Test: Request ending inside a later feed
+	p.Reset()
+	n, err = p.Feed([]byte("GET / HTTP/1.1\r\nHo"))
+	require.NoError(t, err)
+	assert.Equal(t, 18, n)
+	n, err = p.Feed([]byte("st: x\r\n\r\nGET"))
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+	assert.True(t, p.Done())
+}
+
+func TestParserErrors(t *testing.T) {
+	// Test: Errors are typed and sticky until Reset
+	p := NewParser()
+	_, err := p.Feed([]byte("GET / HTTP/2.0\r\n"))
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	_, again := p.Feed([]byte("Host: x\r\n\r\n"))
+	assert.Equal(t, err, again)
+
+	p.Reset()
+	_, err = p.Feed([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, p.Done())
+
+	// Test: Options apply from the next Reset
+	p.Options.Limits.MaxRequestLineBytes = 8
+	p.Reset()
+	_, err = p.Feed([]byte("GET /much/too/long HTTP/1.1\r\n"))
+	assert.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	p.Options = DefaultParserOptions()
+	p.Options.Mode = ModeLenient
+	p.Reset()
+	_, err = p.Feed([]byte("GET  / HTTP/1.1\n\n"))
+	require.NoError(t, err)
+	assert.True(t, p.Done())
+	assert.Len(t, p.Request().Leniencies, 2)
+
+	// Test: The zero Parser works
+	var zero Parser
+	assert.False(t, zero.Done())
+	_, err = zero.Feed([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	assert.True(t, zero.Done())
+}
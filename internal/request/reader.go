@@ -0,0 +1,114 @@
+package request
+
+import (
+	"io"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/stream"
+)
+
+// Reader parses successive requests from one stream, such as a persistent
+// connection. Bytes read past the end of one request are kept and used to
+// start parsing the next, so pipelined requests are not lost.
+type Reader struct {
+	// Options applies to every request read after it is set.
+	Options ParserOptions
+
+	buf stream.Buffer
+	// body is the BodyReader of the last request read with StreamBody set.
+	body *stream.BodyReader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		Options: DefaultParserOptions(),
+		buf:     stream.NewBuffer(r),
+	}
+}
+
+// ReadRequest parses the next request. It returns io.EOF if the stream ends
+// cleanly before a new request starts. Any unread body of the previous
+// request is discarded first.
+func (rr *Reader) ReadRequest() (*Request, error) {
+	if err := rr.closeBody(); err != nil {
+		return nil, err
+	}
+	req := newRequest(rr.Options)
+	err := rr.parseUntil(req, func() bool {
+		return req.ParserState == requestStateDone ||
+			(req.streamBody && req.ParserState > requestStateParsingHeaders)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req.streamBody {
+		rr.body = stream.NewBodyReader(streamedBody{reader: rr, req: req})
+		req.BodyReader = rr.body
+	}
+	return req, nil
+}
+
+// WaitForRequest blocks until the first byte of the next request has
+// arrived, discarding any unread body of the previous request first. It lets
+// a server tell a connection sitting idle from a request arriving slowly. It
+// returns io.EOF if the stream ends before another request starts.
+func (rr *Reader) WaitForRequest() error {
+	if err := rr.closeBody(); err != nil {
+		return err
+	}
+	return rr.buf.Wait()
+}
+
+// closeBody discards the unread body of the last request read with
+// StreamBody set.
+func (rr *Reader) closeBody() error {
+	if rr.body == nil {
+		return nil
+	}
+	if err := rr.body.Close(); err != nil {
+		return err
+	}
+	rr.body = nil
+	return nil
+}
+
+// parseUntil feeds buffered bytes to req, reading more from the stream
+// whenever the buffer runs dry, until stop reports true. The buffer goes
+// back to the pool once a request is done and nothing is left in it.
+func (rr *Reader) parseUntil(req *Request, stop func() bool) error {
+	return rr.buf.ParseUntil(req.parse, func() bool {
+		if !stop() {
+			return false
+		}
+		if rr.buf.Buffered() == 0 && req.ParserState == requestStateDone {
+			rr.buf.Release()
+		}
+		return true
+	}, func() error {
+		if req.ParserState == requestStateInitialized && rr.buf.Buffered() == 0 {
+			return io.EOF
+		}
+		return req.eofError()
+	})
+}
+
+// streamedBody lets a stream.BodyReader pull the body of req through rr.
+type streamedBody struct {
+	reader *Reader
+	req    *Request
+}
+
+func (s streamedBody) TakeBody() []byte {
+	p := s.req.pending
+	s.req.pending = s.req.pending[:0]
+	return p
+}
+
+func (s streamedBody) ParseBody() error {
+	return s.reader.parseUntil(s.req, func() bool {
+		return len(s.req.pending) > 0 || s.req.ParserState == requestStateDone
+	})
+}
+
+func (s streamedBody) Done() bool {
+	return s.req.ParserState == requestStateDone
+}
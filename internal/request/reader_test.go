@@ -0,0 +1,216 @@
+package request
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderPipelining(t *testing.T) {
+	reader := NewReader(&chunkReader{
+		data: "POST /one HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello" +
+			"GET /two HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+			"\r\n" +
+			"POST /three HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n0\r\n\r\n",
+		numBytesPerRead: 7,
+	})
+
+	// Test: Leftover bytes seed the next request
+	r, err := reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/one", r.RequestLine.RequestTarget)
+	assert.Equal(t, "hello", string(r.Body))
+
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/two", r.RequestLine.RequestTarget)
+	assert.Empty(t, r.Body)
+
+	// Test: Stray CRLF between requests is ignored
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/three", r.RequestLine.RequestTarget)
+	assert.Equal(t, "abc", string(r.Body))
+
+	// Test: Clean end of stream
+	_, err = reader.ReadRequest()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestKeepAlive(t *testing.T) {
+	tests := []struct {
+		data      string
+		keepAlive bool
+	}{
+		{"GET / HTTP/1.1\r\n\r\n", true},
+		{"GET / HTTP/1.1\r\nConnection: close\r\n\r\n", false},
+		{"GET / HTTP/1.1\r\nConnection: Upgrade, Close\r\n\r\n", false},
+		{"GET / HTTP/1.0\r\n\r\n", false},
+		{"GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\n", true},
+	}
+	for _, tt := range tests {
+		r, err := RequestFromReader(&chunkReader{data: tt.data, numBytesPerRead: 3})
+		require.NoError(t, err)
+		assert.Equal(t, tt.keepAlive, r.KeepAlive(), tt.data)
+	}
+}
+
+func TestExpectsContinue(t *testing.T) {
+	tests := []struct {
+		data            string
+		expectsContinue bool
+	}{
+		{"POST / HTTP/1.1\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\nhello", true},
+		{"POST / HTTP/1.1\r\nExpect: 100-Continue\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", true},
+		{"POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello", false},
+		{"POST / HTTP/1.1\r\nExpect: 100-continue\r\nContent-Length: 0\r\n\r\n", false},
+		{"POST / HTTP/1.0\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\nhello", false},
+	}
+	for _, tt := range tests {
+		// Test: Decided once the headers are read
+		reader := NewReader(&chunkReader{data: tt.data, numBytesPerRead: 3})
+		reader.Options.StreamBody = true
+		r, err := reader.ReadRequest()
+		require.NoError(t, err)
+		assert.Equal(t, tt.expectsContinue, r.ExpectsContinue(), tt.data)
+
+		// Test: Never once the body has been read
+		_, err = io.ReadAll(r.BodyReader)
+		require.NoError(t, err)
+		assert.False(t, r.ExpectsContinue(), tt.data)
+	}
+}
+
+func TestReaderStreamBody(t *testing.T) {
+	reader := NewReader(&chunkReader{
+		data: "POST /one HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world" +
+			"POST /two HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\nX-Sum: 1\r\n\r\n" +
+			"POST /three HTTP/1.1\r\nContent-Length: 100\r\n\r\nunread body" +
+			"",
+		numBytesPerRead: 4,
+	})
+	reader.Options.StreamBody = true
+
+	// Test: Content-Length body is streamed
+	r, err := reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/one", r.RequestLine.RequestTarget)
+	assert.Nil(t, r.Body)
+	require.NotNil(t, r.BodyReader)
+	body, err := io.ReadAll(r.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(body))
+	require.NoError(t, r.BodyReader.Close())
+
+	// Test: Chunked body is streamed and trailers are parsed at the end
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/two", r.RequestLine.RequestTarget)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(r.BodyReader, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hel", string(buf))
+	body, err = io.ReadAll(r.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "lo world", string(body))
+	sum, ok := r.Trailers.Get("X-Sum")
+	assert.True(t, ok)
+	assert.Equal(t, "1", sum)
+
+	// Test: Body cut short by the end of the stream
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	body, err = io.ReadAll(r.BodyReader)
+	assert.ErrorIs(t, err, ErrBodyTooShort)
+	assert.Equal(t, "unread body", string(body))
+}
+
+func TestReaderStreamBodySkipped(t *testing.T) {
+	reader := NewReader(&chunkReader{
+		data: "POST /one HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world" +
+			"GET /two HTTP/1.1\r\n\r\n",
+		numBytesPerRead: 4,
+	})
+	reader.Options.StreamBody = true
+
+	// Test: Unread body is discarded before the next request
+	r, err := reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/one", r.RequestLine.RequestTarget)
+
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/two", r.RequestLine.RequestTarget)
+	body, err := io.ReadAll(r.BodyReader)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+
+	// Test: Reading after Close fails
+	require.NoError(t, r.BodyReader.Close())
+	_, err = r.BodyReader.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestReaderWaitForRequest(t *testing.T) {
+	reader := NewReader(&chunkReader{
+		data: "POST /one HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello" +
+			"GET /two HTTP/1.1\r\n\r\n",
+		numBytesPerRead: 4,
+	})
+	reader.Options.StreamBody = true
+
+	// Test: First request
+	require.NoError(t, reader.WaitForRequest())
+	r, err := reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/one", r.RequestLine.RequestTarget)
+
+	// Test: Unread body is skipped before waiting for the next request
+	require.NoError(t, reader.WaitForRequest())
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/two", r.RequestLine.RequestTarget)
+
+	// Test: End of stream
+	assert.ErrorIs(t, reader.WaitForRequest(), io.EOF)
+}
+
+func TestReaderBuffer(t *testing.T) {
+	// bufferSize is the size of a pooled read buffer.
+	const bufferSize = 4 << 10
+
+	// Test: A header larger than the pooled buffer grows it without losing
+	// the bytes already read
+	long := strings.Repeat("x", 3*bufferSize)
+	reader := NewReader(&chunkReader{
+		data: "GET /one HTTP/1.1\r\nX-Long: " + long + "\r\n\r\n" +
+			"GET /two HTTP/1.1\r\n\r\n",
+		numBytesPerRead: 1000,
+	})
+	r, err := reader.ReadRequest()
+	require.NoError(t, err)
+	value, _ := r.Headers.Get("X-Long")
+	assert.Equal(t, long, value)
+	r, err = reader.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/two", r.RequestLine.RequestTarget)
+
+	// Test: Pipelined requests that straddle the end of the buffer slide
+	// to the front
+	one := "POST /next HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello"
+	reader = NewReader(&chunkReader{
+		data:            strings.Repeat(one, 3*bufferSize/len(one)),
+		numBytesPerRead: 777,
+	})
+	for range 3 * bufferSize / len(one) {
+		r, err = reader.ReadRequest()
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(r.Body))
+	}
+	_, err = reader.ReadRequest()
+	assert.Equal(t, io.EOF, err)
+
+}
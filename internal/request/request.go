@@ -2,8 +2,10 @@ package request
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/madhu1992blue/httpfromtcp/internal/headers"
@@ -14,22 +16,86 @@ type ParserState int
 const (
 	requestStateInitialized ParserState = iota
 	requestStateParsingHeaders
+	requestStateParsingBody
+	requestStateParsingChunkSize
+	requestStateParsingChunkData
+	requestStateParsingChunkDataEnd
+	requestStateParsingTrailers
 	requestStateDone
 )
 
-const bufferSize = 8
-
 type Request struct {
 	RequestLine RequestLine
-	Headers     headers.Headers
-	Body        []byte
+	Headers     *headers.Headers
+	// Body holds the whole body, unless the request was read with
+	// StreamBody set.
+	Body []byte
+	// BodyReader streams the body when the request was read with StreamBody
+	// set, and is nil otherwise. It stops at the end of this request's body.
+	BodyReader io.ReadCloser
+	// Trailers holds the trailer fields sent after a chunked body.
+	Trailers *headers.Headers
+	// Leniencies lists the quirks a lenient parser accepted in this
+	// request, each once, in the order they were first seen.
+	Leniencies []Leniency
+	// RemoteAddr is the address of the client that sent the request, set
+	// by the server that accepted the connection.
+	RemoteAddr string
+	// TLS describes the connection the request arrived on when it was
+	// served over HTTPS: the negotiated version, cipher suite and the server
+	// name (SNI) the client asked for. It is nil for plain connections.
+	TLS *tls.ConnectionState
 	ParserState
+
+	// pathValues holds the values a router captured from the path.
+	pathValues map[string]string
+
+	// contentLength is the declared body size, read from the headers once
+	// they are complete.
+	contentLength int
+	// chunkRemaining is the number of bytes left in the current chunk of a
+	// chunked body.
+	chunkRemaining int
+	// offset counts the bytes of the request consumed so far.
+	offset int
+	// headerBytes counts the header and trailer bytes consumed so far.
+	headerBytes int
+	// bodyRead counts the decoded body bytes consumed so far.
+	bodyRead int
+	// pending holds decoded body bytes not yet returned by BodyReader when
+	// streaming, in place of Body.
+	pending    []byte
+	streamBody bool
+	limits     Limits
+	mode       ParseMode
+	callbacks  *Callbacks
+	// fields backs Headers and Trailers, so a parsed request does not need
+	// separate allocations for them.
+	fields struct {
+		headers, trailers headers.Headers
+	}
 }
 
 type RequestLine struct {
 	Method        string
 	RequestTarget string
 	HttpVersion   string
+	// Target is RequestTarget parsed into its parts.
+	Target RequestTarget
+}
+
+// methods are the methods of RFC 9110 §9 and PATCH. Parsing one reuses the
+// string here instead of allocating a new one.
+var methods = map[string]string{
+	"GET":     "GET",
+	"HEAD":    "HEAD",
+	"POST":    "POST",
+	"PUT":     "PUT",
+	"DELETE":  "DELETE",
+	"CONNECT": "CONNECT",
+	"OPTIONS": "OPTIONS",
+	"TRACE":   "TRACE",
+	"PATCH":   "PATCH",
 }
 
 func parseRequestLine(dataBytes []byte) (RequestLine, int, error) {
@@ -38,91 +104,205 @@ func parseRequestLine(dataBytes []byte) (RequestLine, int, error) {
 		// Not enough data to parse the request line
 		return RequestLine{}, 0, nil
 	}
-	line := string(dataBytes[:crlfIndex])
+	line := dataBytes[:crlfIndex]
 
-	parts := strings.Fields(line)
-	if len(parts) != 3 {
-		return RequestLine{}, crlfIndex, fmt.Errorf("invalid request line: %s", string(line))
+	// The parts are separated by single spaces; see ModeLenient for
+	// clients that send more.
+	rawMethod, rest, _ := bytes.Cut(line, []byte(" "))
+	rawTarget, rawVersion, ok := bytes.Cut(rest, []byte(" "))
+	if !ok || len(rawMethod) == 0 || len(rawTarget) == 0 || len(rawVersion) == 0 ||
+		bytes.IndexByte(rawVersion, ' ') != -1 {
+		return RequestLine{}, crlfIndex, fmt.Errorf("%w: %q", ErrInvalidRequestLine, line)
 	}
-	method := parts[0]
-	for _, c := range method {
+	for _, c := range rawMethod {
 		if c < 'A' || c > 'Z' {
-			return RequestLine{}, crlfIndex, fmt.Errorf("invalid method: %s", method)
+			return RequestLine{}, crlfIndex, fmt.Errorf("%w: %q", ErrInvalidMethod, rawMethod)
 		}
 	}
-	requestTarget := parts[1]
-	httpVersion := parts[2]
-	if !strings.HasPrefix(httpVersion, "HTTP/") {
-		return RequestLine{}, crlfIndex, fmt.Errorf("invalid HTTP version: %s", httpVersion)
+	method, ok := methods[string(rawMethod)]
+	if !ok {
+		method = string(rawMethod)
 	}
-	httpVersion = strings.TrimPrefix(httpVersion, "HTTP/")
-	if httpVersion != "1.0" && httpVersion != "1.1" {
-		return RequestLine{}, crlfIndex, fmt.Errorf("unsupported HTTP version: %s", httpVersion)
+	version, ok := bytes.CutPrefix(rawVersion, []byte("HTTP/"))
+	if !ok {
+		return RequestLine{}, crlfIndex, fmt.Errorf("%w: %q", ErrInvalidVersion, rawVersion)
+	}
+	var httpVersion string
+	switch string(version) {
+	case "1.0":
+		httpVersion = "1.0"
+	case "1.1":
+		httpVersion = "1.1"
+	default:
+		return RequestLine{}, crlfIndex, fmt.Errorf("%w: %s", ErrUnsupportedVersion, version)
+	}
+	requestTarget := string(rawTarget)
+	target, err := ParseRequestTarget(method, requestTarget)
+	if err != nil {
+		return RequestLine{}, crlfIndex, err
 	}
 	return RequestLine{
 		Method:        method,
 		RequestTarget: requestTarget,
 		HttpVersion:   httpVersion,
-	}, crlfIndex, nil
+		Target:        target,
+	}, crlfIndex + 2, nil
 }
+
+// RequestFromReader parses a single request from r with the default parser
+// options. Use a Reader to parse several requests from the same connection
+// or to change the options.
 func RequestFromReader(r io.Reader) (*Request, error) {
-	buf := make([]byte, bufferSize)
-	readToIndex := 0
-	req := Request{
+	rr := NewReader(r)
+	defer rr.buf.Release()
+	return rr.ReadRequest()
+}
+
+func newRequest(opts ParserOptions) *Request {
+	r := &Request{
 		ParserState: requestStateInitialized,
-		Headers:     headers.NewHeaders(),
+		streamBody:  opts.StreamBody,
+		limits:      opts.Limits,
+		mode:        opts.Mode,
+		callbacks:   opts.Callbacks,
 	}
-	for req.ParserState != requestStateDone {
-		if readToIndex == len(buf) {
-			newBuf := make([]byte, len(buf)*2)
-			copy(newBuf, buf)
-			buf = newBuf
-		}
-		index, err := r.Read(buf[readToIndex:])
+	r.Headers = &r.fields.headers
+	r.Trailers = &r.fields.trailers
+	return r
+}
+
+// PathValue returns the path value stored under name by a router, or an
+// empty string.
+func (r *Request) PathValue(name string) string {
+	return r.pathValues[name]
+}
+
+// LookupPathValue returns the path value stored under name by a router, and
+// whether there is one. It tells an empty value, such as a "*" that matched
+// nothing, from a missing one.
+func (r *Request) LookupPathValue(name string) (string, bool) {
+	value, ok := r.pathValues[name]
+	return value, ok
+}
+
+// SetPathValue stores a path value, such as the id captured by a router
+// pattern like "/users/{id}".
+func (r *Request) SetPathValue(name, value string) {
+	if r.pathValues == nil {
+		r.pathValues = make(map[string]string)
+	}
+	r.pathValues[name] = value
+}
+
+// KeepAlive reports whether the client expects the connection to stay open
+// after this request. HTTP/1.1 connections are persistent unless the client
+// sends "Connection: close"; HTTP/1.0 ones only with "Connection: keep-alive".
+func (r *Request) KeepAlive() bool {
+	if r.Headers.HasToken("connection", "close") {
+		return false
+	}
+	if r.RequestLine.HttpVersion == "1.0" {
+		return r.Headers.HasToken("connection", "keep-alive")
+	}
+	return true
+}
+
+// ExpectsContinue reports whether the client sent "Expect: 100-continue"
+// and is holding back the body until it gets a 100 Continue. It is false
+// for HTTP/1.0 requests, which must ignore the expectation, and once there
+// is no body left to read.
+func (r *Request) ExpectsContinue() bool {
+	if r.RequestLine.HttpVersion == "1.0" || r.ParserState == requestStateDone {
+		return false
+	}
+	value, ok := r.Headers.Get("expect")
+	return ok && strings.EqualFold(strings.TrimSpace(value), "100-continue")
+}
+
+// parse feeds data through the state machine until it is done or needs more
+// data, and returns the number of bytes consumed. Failures are returned as a
+// *ParseError.
+func (r *Request) parse(data []byte) (int, error) {
+	if r.ParserState == requestStateDone {
+		return 0, nil
+	}
+	totalParsed := 0
+	for r.ParserState != requestStateDone {
+		n, err := r.parseSingle(data[totalParsed:])
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, fmt.Errorf("error reading from reader: %w", err)
+			return 0, newParseError(r.offset, r.ParserState, err)
 		}
-		readToIndex += index
-		parsedSoFar, err := req.parse(buf[:readToIndex])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing request: %w", err)
+		if n == 0 {
+			break
 		}
-		newBuf := make([]byte, len(buf)-parsedSoFar)
-		copy(newBuf, buf[parsedSoFar:])
-		buf = newBuf
-		readToIndex -= parsedSoFar
-
+		totalParsed += n
+		r.offset += n
 	}
-	return &req, nil
+	if r.ParserState == requestStateDone {
+		if err := r.callbacks.onMessageComplete(); err != nil {
+			return 0, newParseError(r.offset, r.ParserState, err)
+		}
+	}
+	return totalParsed, nil
 }
 
-func (r *Request) parse(data []byte) (int, error) {
+func (r *Request) parseSingle(data []byte) (int, error) {
 	if r.ParserState == requestStateDone {
 		return 0, fmt.Errorf("error: trying to read data in a done state")
 	}
+	if r.mode == ModeLenient && r.atLineStart() {
+		return r.parseLenientLine(data)
+	}
+	return r.parseStep(data)
+}
+
+// parseStep handles a single step of the state machine on well-formed data.
+func (r *Request) parseStep(data []byte) (int, error) {
 	switch r.ParserState {
 	case requestStateInitialized:
+		if bytes.HasPrefix(data, []byte("\r\n")) {
+			// Ignore empty lines before the request line, such as a stray
+			// CRLF after the previous request on the connection.
+			return 2, nil
+		}
+		if r.mode == ModeStrict {
+			if err := checkLineEnding(data); err != nil {
+				return 0, err
+			}
+		}
 		reqLine, offset, err := parseRequestLine(data)
 		if err != nil {
 			return 0, err
 		}
+		lineLength := offset - 2
+		if offset == 0 {
+			lineLength = len(data)
+		}
+		if exceeds(lineLength, r.limits.MaxRequestLineBytes) {
+			return 0, errRequestLineTooLong(r.limits.MaxRequestLineBytes)
+		}
 		if offset == 0 {
 			// More data is needed to parse the request line
 			return 0, nil
 		}
 		r.RequestLine = reqLine
 		r.ParserState = requestStateParsingHeaders
+		if err := r.callbacks.onRequestLine(reqLine); err != nil {
+			return 0, err
+		}
 		return offset, nil
 	case requestStateParsingHeaders:
-		offset, done, err := r.Headers.Parse(data)
+		offset, done, err := r.parseField(r.Headers, data)
 		if err != nil {
 			return 0, err
 		}
 		if done {
-			r.ParserState = requestStateDone
+			if err := r.startBody(); err != nil {
+				return 0, err
+			}
+			if err := r.callbacks.onHeadersComplete(); err != nil {
+				return 0, err
+			}
 			return offset, nil
 		}
 		if offset == 0 {
@@ -130,7 +310,140 @@ func (r *Request) parse(data []byte) (int, error) {
 			return 0, nil
 		}
 		return offset, nil
+	case requestStateParsingBody:
+		remaining := r.contentLength - r.bodyRead
+		n := min(remaining, len(data))
+		if err := r.appendBody(data[:n]); err != nil {
+			return 0, err
+		}
+		if r.bodyRead == r.contentLength {
+			r.ParserState = requestStateDone
+		}
+		return n, nil
+	case requestStateParsingChunkSize,
+		requestStateParsingChunkData,
+		requestStateParsingChunkDataEnd,
+		requestStateParsingTrailers:
+		return r.parseChunked(data)
 	}
 
 	return 0, fmt.Errorf("unknown parser state: %d", r.ParserState)
 }
+
+// parseField parses one field line into h, or the empty line that ends the
+// section, while keeping the section inside the header limits.
+func (r *Request) parseField(h *headers.Headers, data []byte) (int, bool, error) {
+	if r.mode == ModeStrict {
+		if err := checkStrictField(data); err != nil {
+			return 0, false, err
+		}
+	}
+	offset, done, err := h.Parse(data)
+	if err != nil {
+		return 0, false, err
+	}
+	if offset == 0 {
+		if exceeds(r.headerBytes+len(data), r.limits.MaxHeaderBytes) {
+			return 0, false, errHeadersTooLarge(r.limits.MaxHeaderBytes)
+		}
+		return 0, false, nil
+	}
+	r.headerBytes += offset
+	if exceeds(r.headerBytes, r.limits.MaxHeaderBytes) {
+		return 0, false, errHeadersTooLarge(r.limits.MaxHeaderBytes)
+	}
+	if exceeds(r.Headers.Len()+r.Trailers.Len(), r.limits.MaxHeaderCount) {
+		return 0, false, errTooManyHeaders(r.limits.MaxHeaderCount)
+	}
+	if !done {
+		name, value, _ := h.Last()
+		if err := r.callbacks.onField(name, value); err != nil {
+			return 0, false, err
+		}
+	}
+	return offset, done, nil
+}
+
+// appendBody stores decoded body bytes where the caller will look for them,
+// or hands them to OnBodyChunk.
+func (r *Request) appendBody(p []byte) error {
+	r.bodyRead += len(p)
+	if r.callbacks.takesBody() {
+		if len(p) == 0 {
+			return nil
+		}
+		return r.callbacks.OnBodyChunk(p)
+	}
+	if r.streamBody {
+		r.pending = append(r.pending, p...)
+	} else {
+		r.Body = append(r.Body, p...)
+	}
+	return nil
+}
+
+// maxBodyPrealloc bounds the room reserved for a body up front, so that a
+// large Content-Length does not cost memory before the body arrives.
+const maxBodyPrealloc = 64 << 10
+
+// startBody picks the body framing once the headers are complete and moves
+// the parser into the matching state.
+func (r *Request) startBody() error {
+	chunked, err := isChunked(r.Headers)
+	if err != nil {
+		return err
+	}
+	_, hasContentLength := r.Headers.Get("content-length")
+	if chunked {
+		if hasContentLength {
+			return ErrContentLengthWithChunked
+		}
+		r.ParserState = requestStateParsingChunkSize
+		return nil
+	}
+	if r.mode == ModeStrict {
+		if err := r.checkStrictFraming(); err != nil {
+			return err
+		}
+	}
+	contentLength, err := parseContentLength(r.Headers)
+	if err != nil {
+		return err
+	}
+	if exceeds(contentLength, r.maxBodyBytes()) {
+		return errBodyTooLarge(r.maxBodyBytes())
+	}
+	r.contentLength = contentLength
+	if !r.streamBody && !r.callbacks.takesBody() {
+		r.Body = make([]byte, 0, min(contentLength, maxBodyPrealloc))
+	}
+	if contentLength == 0 {
+		r.ParserState = requestStateDone
+	} else {
+		r.ParserState = requestStateParsingBody
+	}
+	return nil
+}
+
+// parseContentLength returns the body size declared by the Content-Length
+// header, or 0 when the header is absent. A repeated header, or a list such as
+// "5, 5", is only accepted when every value is the same.
+func parseContentLength(h *headers.Headers) (int, error) {
+	value, ok := h.Get("content-length")
+	if !ok {
+		return 0, nil
+	}
+	contentLength := -1
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		parsed, err := strconv.Atoi(item)
+		if err != nil || strings.TrimLeft(item, "0123456789") != "" {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidContentLength, value)
+		}
+		if contentLength != -1 && parsed != contentLength {
+			return 0, fmt.Errorf("%w: %q", ErrConflictingContentLength, value)
+		}
+		contentLength = parsed
+	}
+	return contentLength, nil
+}
@@ -0,0 +1,77 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseMode chooses how forgiving the parser is of malformed requests.
+type ParseMode int
+
+const (
+	// ModeDefault accepts what the parser has always accepted.
+	ModeDefault ParseMode = iota
+	// ModeStrict rejects every construct that HTTP implementations are
+	// known to frame differently, which lets an attacker smuggle a request
+	// past a proxy sitting in front of another server: bare LF line
+	// endings, obs-fold continuation lines, whitespace before a field's
+	// colon, repeated Content-Length values, Content-Length together with
+	// Transfer-Encoding, and final transfer codings other than chunked.
+	// Each is reported with its own error, so attempts can be audited.
+	ModeStrict
+	// ModeLenient accepts the quirks of clients such as embedded devices:
+	// bare LF line endings, runs of spaces or tabs between the parts of the
+	// request line, and obs-fold continuation lines, which are joined to the
+	// field above with a single space. Each quirk accepted is recorded in
+	// Request.Leniencies, so the clients sending them can be found.
+	ModeLenient
+)
+
+var (
+	// ErrBareLF is returned in strict mode for a line ending in LF alone
+	// instead of CRLF.
+	ErrBareLF = errors.New("bare LF line ending")
+	// ErrObsFold is returned in strict mode for a field line starting with
+	// whitespace, which obs-fold used to continue the previous field.
+	ErrObsFold = errors.New("obsolete line folding")
+	// ErrDuplicateContentLength is returned in strict mode when
+	// Content-Length is repeated with matching values. Differing values are
+	// reported as ErrConflictingContentLength in every mode.
+	ErrDuplicateContentLength = errors.New("duplicate content-length")
+)
+
+// checkLineEnding fails if the first line in data ends with a bare LF. A
+// line that is not complete yet passes.
+func checkLineEnding(data []byte) error {
+	i := bytes.IndexByte(data, '\n')
+	if i == -1 || (i > 0 && data[i-1] == '\r') {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrBareLF, data[:i+1])
+}
+
+// checkStrictField applies the strict checks to the next field line, or the
+// empty line ending the section, before it is parsed.
+func checkStrictField(data []byte) error {
+	if len(data) > 0 && (data[0] == ' ' || data[0] == '\t') {
+		return ErrObsFold
+	}
+	return checkLineEnding(data)
+}
+
+// checkStrictFraming applies the strict checks to the headers that decide
+// how the body is framed. A repeated Content-Length is rejected with the
+// error that names the vector: invalid or conflicting values first, and
+// ErrDuplicateContentLength only when they all agree.
+func (r *Request) checkStrictFraming() error {
+	values := r.Headers.Values("content-length")
+	if len(values) > 1 || (len(values) == 1 && strings.Contains(values[0], ",")) {
+		if _, err := parseContentLength(r.Headers); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %q", ErrDuplicateContentLength, strings.Join(values, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readWithMode(data string, mode ParseMode) (*Request, error) {
+	reader := NewReader(&chunkReader{data: data, numBytesPerRead: 3})
+	reader.Options.Mode = mode
+	return reader.ReadRequest()
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{
+			"content-length with chunked",
+			"POST / HTTP/1.1\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n",
+			ErrContentLengthWithChunked,
+		},
+		{
+			"duplicate content-length fields",
+			"POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 5\r\n\r\nhello",
+			ErrDuplicateContentLength,
+		},
+		{
+			"content-length list",
+			"POST / HTTP/1.1\r\nContent-Length: 5, 5\r\n\r\nhello",
+			ErrDuplicateContentLength,
+		},
+		{
+			"conflicting content-length",
+			"POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\nhello!",
+			ErrConflictingContentLength,
+		},
+		{
+			"conflicting content-length list",
+			"POST / HTTP/1.1\r\nContent-Length: 5, 6\r\n\r\nhello!",
+			ErrConflictingContentLength,
+		},
+		{
+			"obs-fold",
+			"GET / HTTP/1.1\r\nX-Long: first\r\n second\r\n\r\n",
+			ErrObsFold,
+		},
+		{
+			"whitespace before the first field",
+			"GET / HTTP/1.1\r\n Host: example.com\r\n\r\n",
+			ErrObsFold,
+		},
+		{
+			"bare LF after the request line",
+			"GET / HTTP/1.1\nHost: example.com\r\n\r\n",
+			ErrBareLF,
+		},
+		{
+			"bare LF after a field",
+			"GET / HTTP/1.1\r\nHost: example.com\nX-Smuggled: yes\r\n\r\n",
+			ErrBareLF,
+		},
+		{
+			"bare LF ending the headers",
+			"GET / HTTP/1.1\r\nHost: example.com\r\n\n",
+			ErrBareLF,
+		},
+		{
+			"bare LF after a chunk size",
+			"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\nhello\r\n0\r\n\r\n",
+			ErrBareLF,
+		},
+		{
+			"bare LF in trailers",
+			"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nX-Sum: 1\n\r\n",
+			ErrBareLF,
+		},
+		{
+			"space before colon",
+			"GET / HTTP/1.1\r\nHost : example.com\r\n\r\n",
+			ErrSpaceBeforeColon,
+		},
+		{
+			"tab before colon",
+			"GET / HTTP/1.1\r\nHost\t: example.com\r\n\r\n",
+			ErrSpaceBeforeColon,
+		},
+		{
+			"non-chunked final coding",
+			"POST / HTTP/1.1\r\nTransfer-Encoding: chunked, gzip\r\n\r\n",
+			ErrUnsupportedTransferEncoding,
+		},
+	}
+	for _, tt := range tests {
+		// Test: Each desync vector has its own error
+		_, err := readWithMode(tt.data, ModeStrict)
+		require.Error(t, err, tt.name)
+		assert.ErrorIs(t, err, tt.err, tt.name)
+		for _, other := range tests {
+			if other.err != tt.err {
+				assert.NotErrorIs(t, err, other.err, tt.name)
+			}
+		}
+	}
+
+	// Test: Well-formed requests pass
+	for _, data := range []string{
+		"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello",
+		"POST / HTTP/1.1\r\nTransfer-Encoding: gzip, chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Sum: 1\r\n\r\n",
+		"\r\nGET / HTTP/1.1\r\nX-Value: a\tb c\r\n\r\n",
+	} {
+		_, err := readWithMode(data, ModeStrict)
+		assert.NoError(t, err, data)
+	}
+}
+
+func TestDefaultModeIsNotStrict(t *testing.T) {
+	// Test: Matching duplicate Content-Length values are accepted
+	r, err := readWithMode("POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 5\r\n\r\nhello", ModeDefault)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(r.Body))
+
+	// Test: Leading whitespace on a field line is trimmed
+	r, err = readWithMode("GET / HTTP/1.1\r\n Host: example.com\r\n\r\n", ModeDefault)
+	require.NoError(t, err)
+	host, _ := r.Headers.Get("Host")
+	assert.Equal(t, "example.com", host)
+}
@@ -0,0 +1,260 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TargetForm is one of the four request-target forms of RFC 9112 §3.2.
+type TargetForm int
+
+const (
+	// OriginForm is an absolute path with an optional query: "/where?q=now".
+	OriginForm TargetForm = iota
+	// AbsoluteForm is a full URI, sent to proxies: "http://example.com/".
+	AbsoluteForm
+	// AuthorityForm is host and port, used only by CONNECT: "example.com:443".
+	AuthorityForm
+	// AsteriskForm is "*", used only by a server-wide OPTIONS.
+	AsteriskForm
+)
+
+func (f TargetForm) String() string {
+	switch f {
+	case OriginForm:
+		return "origin-form"
+	case AbsoluteForm:
+		return "absolute-form"
+	case AuthorityForm:
+		return "authority-form"
+	case AsteriskForm:
+		return "asterisk-form"
+	}
+	return fmt.Sprintf("TargetForm(%d)", int(f))
+}
+
+var (
+	ErrInvalidTarget        = errors.New("invalid request target")
+	ErrTargetFormNotAllowed = errors.New("request target form not allowed for method")
+)
+
+// Query maps each query parameter to its values in the order they appeared.
+type Query map[string][]string
+
+// Get returns the first value for key, or an empty string.
+func (q Query) Get(key string) string {
+	if values := q[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// RequestTarget is the parsed form of RequestLine.RequestTarget.
+type RequestTarget struct {
+	Form TargetForm
+	// Scheme is set for AbsoluteForm only.
+	Scheme string
+	// Authority is the host with an optional port, set for AbsoluteForm and
+	// AuthorityForm.
+	Authority string
+	// Path is the percent-decoded path. RawPath keeps it as sent, which
+	// matters when it contains an encoded "/".
+	Path    string
+	RawPath string
+	// RawQuery is the query as sent, without the "?".
+	RawQuery string
+	Query    Query
+}
+
+// PathSegments returns the segments of the path after its leading "/",
+// each percent-decoded on its own, so that an encoded "/" stays inside its
+// segment. It returns nil for the forms that have no path.
+func (t RequestTarget) PathSegments() []string {
+	if !strings.HasPrefix(t.RawPath, "/") {
+		return nil
+	}
+	segments := strings.Split(t.RawPath[1:], "/")
+	for i, segment := range segments {
+		// The escapes in RawPath were checked when the target was parsed.
+		segments[i], _ = percentDecode(segment, false)
+	}
+	return segments
+}
+
+// ParseRequestTarget parses target and checks that its form is one method
+// may use: authority-form only with CONNECT, asterisk-form only with OPTIONS.
+func ParseRequestTarget(method, target string) (RequestTarget, error) {
+	for i := 0; i < len(target); i++ {
+		if target[i] <= ' ' || target[i] >= 0x7f || target[i] == '#' {
+			return RequestTarget{}, fmt.Errorf("%w: %q", ErrInvalidTarget, target)
+		}
+	}
+	var parsed RequestTarget
+	var err error
+	switch {
+	case method == "CONNECT":
+		parsed, err = parseAuthorityForm(target)
+	case target == "*":
+		parsed = RequestTarget{Form: AsteriskForm}
+	case strings.HasPrefix(target, "/"):
+		parsed, err = parseOriginForm(target)
+	default:
+		parsed, err = parseAbsoluteForm(target)
+	}
+	if err != nil {
+		return RequestTarget{}, err
+	}
+	if parsed.Form == AsteriskForm && method != "OPTIONS" {
+		return RequestTarget{}, fmt.Errorf("%w: %s %s", ErrTargetFormNotAllowed, method, parsed.Form)
+	}
+	return parsed, nil
+}
+
+func parseOriginForm(target string) (RequestTarget, error) {
+	rawPath, rawQuery, _ := strings.Cut(target, "?")
+	path, err := percentDecode(rawPath, false)
+	if err != nil {
+		return RequestTarget{}, err
+	}
+	query, err := parseQuery(rawQuery)
+	if err != nil {
+		return RequestTarget{}, err
+	}
+	return RequestTarget{
+		Form:     OriginForm,
+		Path:     path,
+		RawPath:  rawPath,
+		RawQuery: rawQuery,
+		Query:    query,
+	}, nil
+}
+
+func parseAbsoluteForm(target string) (RequestTarget, error) {
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok || !validScheme(scheme) {
+		return RequestTarget{}, fmt.Errorf("%w: %q", ErrInvalidTarget, target)
+	}
+	end := strings.IndexAny(rest, "/?")
+	if end == -1 {
+		end = len(rest)
+	}
+	authority := rest[:end]
+	if authority == "" || strings.Contains(authority, "@") {
+		// Userinfo is deprecated in http(s) URIs and only used for phishing.
+		return RequestTarget{}, fmt.Errorf("%w: %q", ErrInvalidTarget, target)
+	}
+	originTarget := rest[end:]
+	if !strings.HasPrefix(originTarget, "/") {
+		originTarget = "/" + originTarget
+	}
+	parsed, err := parseOriginForm(originTarget)
+	if err != nil {
+		return RequestTarget{}, err
+	}
+	parsed.Form = AbsoluteForm
+	parsed.Scheme = strings.ToLower(scheme)
+	parsed.Authority = authority
+	return parsed, nil
+}
+
+func parseAuthorityForm(target string) (RequestTarget, error) {
+	i := strings.LastIndexByte(target, ':')
+	if i <= 0 || i == len(target)-1 || strings.ContainsAny(target, "/?@") {
+		return RequestTarget{}, fmt.Errorf("%w: CONNECT needs host:port, got %q", ErrTargetFormNotAllowed, target)
+	}
+	for _, c := range target[i+1:] {
+		if c < '0' || c > '9' {
+			return RequestTarget{}, fmt.Errorf("%w: invalid port in %q", ErrInvalidTarget, target)
+		}
+	}
+	return RequestTarget{
+		Form:      AuthorityForm,
+		Authority: target,
+	}, nil
+}
+
+// validScheme checks scheme = ALPHA *( ALPHA / DIGIT / "+" / "-" / "." ).
+func validScheme(scheme string) bool {
+	if scheme == "" {
+		return false
+	}
+	for i, c := range scheme {
+		isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if i == 0 && !isAlpha {
+			return false
+		}
+		if !isAlpha && !(c >= '0' && c <= '9') && c != '+' && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseQuery splits a query such as "a=1&b=x+y&a=2" into its parameters,
+// decoding each key and value. A "+" decodes to a space, as in HTML forms.
+// An empty query gives a nil Query.
+func parseQuery(rawQuery string) (Query, error) {
+	var query Query
+	for rawQuery != "" {
+		var pair string
+		pair, rawQuery, _ = strings.Cut(rawQuery, "&")
+		if pair == "" {
+			continue
+		}
+		if query == nil {
+			query = Query{}
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := percentDecode(rawKey, true)
+		if err != nil {
+			return nil, err
+		}
+		value, err := percentDecode(rawValue, true)
+		if err != nil {
+			return nil, err
+		}
+		query[key] = append(query[key], value)
+	}
+	return query, nil
+}
+
+// percentDecode replaces each %XX escape with the byte it encodes, and each
+// "+" with a space when plusIsSpace is set.
+func percentDecode(s string, plusIsSpace bool) (string, error) {
+	if !strings.ContainsAny(s, "%+") {
+		return s, nil
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return "", fmt.Errorf("%w: bad escape in %q", ErrInvalidTarget, s)
+			}
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+		case s[i] == '+' && plusIsSpace:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
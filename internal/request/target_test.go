@@ -0,0 +1,103 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestTarget(t *testing.T) {
+	// Test: Origin-form with a query
+	target, err := ParseRequestTarget("GET", "/search%20results/a%2Fb?q=go+lang&tag=a&tag=b%26c&flag")
+	require.NoError(t, err)
+	assert.Equal(t, OriginForm, target.Form)
+	assert.Equal(t, "/search results/a/b", target.Path)
+	assert.Equal(t, "/search%20results/a%2Fb", target.RawPath)
+	assert.Equal(t, "q=go+lang&tag=a&tag=b%26c&flag", target.RawQuery)
+	assert.Equal(t, "go lang", target.Query.Get("q"))
+	assert.Equal(t, []string{"a", "b&c"}, target.Query["tag"])
+	assert.Equal(t, []string{""}, target.Query["flag"])
+	assert.Equal(t, "", target.Query.Get("missing"))
+
+	// Test: An encoded slash stays inside its path segment
+	assert.Equal(t, []string{"search results", "a/b"}, target.PathSegments())
+
+	// Test: Plus stays a plus in the path
+	target, err = ParseRequestTarget("GET", "/c++")
+	require.NoError(t, err)
+	assert.Equal(t, "/c++", target.Path)
+
+	// Test: No query gives a nil Query that still answers Get
+	assert.Nil(t, target.Query)
+	assert.Equal(t, "", target.Query.Get("q"))
+
+	// Test: Absolute-form
+	target, err = ParseRequestTarget("GET", "HTTP://example.com:8080/index.html?x=1")
+	require.NoError(t, err)
+	assert.Equal(t, AbsoluteForm, target.Form)
+	assert.Equal(t, "http", target.Scheme)
+	assert.Equal(t, "example.com:8080", target.Authority)
+	assert.Equal(t, "/index.html", target.Path)
+	assert.Equal(t, "1", target.Query.Get("x"))
+
+	// Test: Absolute-form with an empty path
+	target, err = ParseRequestTarget("GET", "http://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "/", target.Path)
+
+	// Test: Authority-form
+	target, err = ParseRequestTarget("CONNECT", "example.com:443")
+	require.NoError(t, err)
+	assert.Equal(t, AuthorityForm, target.Form)
+	assert.Equal(t, "example.com:443", target.Authority)
+
+	// Test: Asterisk-form
+	target, err = ParseRequestTarget("OPTIONS", "*")
+	require.NoError(t, err)
+	assert.Equal(t, AsteriskForm, target.Form)
+
+	// Test: Forms the method does not allow
+	for _, tt := range []struct{ method, target string }{
+		{"GET", "*"},
+		{"CONNECT", "/"},
+		{"CONNECT", "http://example.com/"},
+		{"CONNECT", "example.com"},
+	} {
+		_, err = ParseRequestTarget(tt.method, tt.target)
+		assert.ErrorIs(t, err, ErrTargetFormNotAllowed, "%s %s", tt.method, tt.target)
+	}
+
+	// Test: Malformed targets
+	for _, raw := range []string{
+		"coffee",
+		"/bad%zzescape",
+		"/trailing%2",
+		"/with#fragment",
+		"http://user@example.com/",
+		"1http://example.com/",
+		"http:///nohost",
+	} {
+		_, err = ParseRequestTarget("GET", raw)
+		assert.ErrorIs(t, err, ErrInvalidTarget, raw)
+	}
+}
+
+func TestRequestLineTarget(t *testing.T) {
+	// Test: Target is parsed along with the request line
+	r, err := RequestFromReader(&chunkReader{
+		data:            "GET /coffee?size=large HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/coffee?size=large", r.RequestLine.RequestTarget)
+	assert.Equal(t, "/coffee", r.RequestLine.Target.Path)
+	assert.Equal(t, "large", r.RequestLine.Target.Query.Get("size"))
+
+	// Test: A form the method does not allow fails the request
+	_, err = RequestFromReader(&chunkReader{
+		data:            "GET * HTTP/1.1\r\n\r\n",
+		numBytesPerRead: 3,
+	})
+	assert.ErrorIs(t, err, ErrTargetFormNotAllowed)
+}
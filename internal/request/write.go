@@ -0,0 +1,74 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/chunked"
+)
+
+// WriteTo writes the request in wire format, in a single write so small
+// requests go out in one packet. See Bytes for the format.
+func (r *Request) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r.Bytes())
+	return int64(n), err
+}
+
+// Bytes returns the request in wire format: the request line, the headers
+// in their original order and casing, and Body framed the way the headers
+// say. A chunked body is written as a single chunk followed by the
+// trailers; otherwise Content-Length is corrected, or added, to match Body.
+// Parsing a well-formed request with a Content-Length body, or a chunked
+// body sent as one chunk, and writing it back gives the same bytes. A body
+// left in BodyReader is not included.
+func (r *Request) Bytes() []byte {
+	var buf bytes.Buffer
+	version := r.RequestLine.HttpVersion
+	if version == "" {
+		version = "1.1"
+	}
+	fmt.Fprintf(&buf, "%s %s HTTP/%s\r\n", r.RequestLine.Method, r.RequestLine.RequestTarget, version)
+
+	if r.Headers == nil {
+		if len(r.Body) > 0 {
+			fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(r.Body))
+		}
+		buf.WriteString("\r\n")
+		buf.Write(r.Body)
+		return buf.Bytes()
+	}
+
+	isChunked, _ := chunked.IsChunked(r.Headers)
+	contentLength, err := parseContentLength(r.Headers)
+	_, hasContentLength := r.Headers.Get("content-length")
+	fixLength := !isChunked && hasContentLength && (err != nil || contentLength != len(r.Body))
+	for name, value := range r.Headers.All() {
+		if fixLength && strings.EqualFold(name, "content-length") {
+			value = strconv.Itoa(len(r.Body))
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	if !isChunked && !hasContentLength && len(r.Body) > 0 {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(r.Body))
+	}
+	buf.WriteString("\r\n")
+
+	if !isChunked {
+		buf.Write(r.Body)
+		return buf.Bytes()
+	}
+	if len(r.Body) > 0 {
+		fmt.Fprintf(&buf, "%x\r\n", len(r.Body))
+		buf.Write(r.Body)
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("0\r\n")
+	if r.Trailers != nil {
+		r.Trailers.WriteTo(&buf)
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,71 @@
+package request
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"no body", "GET / HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n"},
+		{"original casing and order", "GET /a?b=c HTTP/1.1\r\nhost: example.com\r\nX-Request-ID: 42\r\naccept: */*\r\nACCEPT: text/html\r\n\r\n"},
+		{"content-length body", "POST /submit HTTP/1.1\r\nHost: localhost\r\nContent-Length: 13\r\n\r\nhello world!\n"},
+		{"http/1.0", "GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"},
+		{"chunked with trailers", "POST /upload HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\nTrailer: X-Sum\r\n\r\nb\r\nhello world\r\n0\r\nX-Sum: 5eb63bbb\r\n\r\n"},
+		{"empty chunked body", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n"},
+		{"absolute form", "GET http://example.com/x HTTP/1.1\r\nHost: example.com\r\n\r\n"},
+	}
+	for _, tt := range tests {
+		// Test: Parse then serialize gives the same bytes
+		r, err := RequestFromReader(&chunkReader{data: tt.raw, numBytesPerRead: 3})
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.raw, string(r.Bytes()), tt.name)
+
+		var buf bytes.Buffer
+		n, err := r.WriteTo(&buf)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, int64(len(tt.raw)), n, tt.name)
+		assert.Equal(t, tt.raw, buf.String(), tt.name)
+	}
+}
+
+func TestBytesFraming(t *testing.T) {
+	// Test: Multi-chunk body is written as one chunk
+	r, err := RequestFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n",
+		numBytesPerRead: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nb\r\nhello world\r\n0\r\n\r\n", string(r.Bytes()))
+
+	// Test: Content-Length is corrected after the body changes
+	r, err = RequestFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nContent-Length: 5\r\nHost: x\r\n\r\nhello",
+		numBytesPerRead: 3,
+	})
+	require.NoError(t, err)
+	r.Body = []byte("hi")
+	assert.Equal(t, "POST / HTTP/1.1\r\nContent-Length: 2\r\nHost: x\r\n\r\nhi", string(r.Bytes()))
+
+	// Test: Content-Length is added to a built request with a body
+	r = &Request{
+		RequestLine: RequestLine{Method: "PUT", RequestTarget: "/item", HttpVersion: "1.1"},
+		Headers:     headers.NewHeaders(),
+		Body:        []byte("data"),
+	}
+	r.Headers.Set("Host", "x")
+	assert.Equal(t, "PUT /item HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\ndata", string(r.Bytes()))
+
+	// Test: Output parses back to the same request
+	parsed, err := RequestFromReader(bytes.NewReader(r.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, r.RequestLine.Method, parsed.RequestLine.Method)
+	assert.Equal(t, "data", string(parsed.Body))
+}
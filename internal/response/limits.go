@@ -0,0 +1,57 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits bounds how much of a response the parser accepts, so an untrusted
+// upstream cannot exhaust memory by never ending a line or a body. A zero
+// field means no limit. They mirror request.Limits.
+type Limits struct {
+	// MaxStatusLineBytes bounds the status line, excluding its CRLF.
+	MaxStatusLineBytes int
+	// MaxHeaderBytes bounds the whole header section, including the CRLFs
+	// and any trailer section after a chunked body.
+	MaxHeaderBytes int
+	// MaxHeaderCount bounds the number of header and trailer field lines.
+	MaxHeaderCount int
+	// MaxBodyBytes bounds the decoded body kept in Response.Body. A body
+	// read through BodyReader is not buffered, so it is not bounded.
+	MaxBodyBytes int
+}
+
+// DefaultLimits are the limits used by ResponseFromReader and new Readers.
+var DefaultLimits = Limits{
+	MaxStatusLineBytes: 8 << 10,
+	MaxHeaderBytes:     64 << 10,
+	MaxHeaderCount:     100,
+	MaxBodyBytes:       10 << 20,
+}
+
+var (
+	ErrStatusLineTooLong = errors.New("status line too long")
+	ErrHeadersTooLarge   = errors.New("header section too large")
+	ErrTooManyHeaders    = errors.New("too many header fields")
+	ErrBodyTooLarge      = errors.New("body too large")
+)
+
+// LimitError is returned when a response exceeds one of its Limits. It
+// wraps one of the Err*TooLong/TooLarge/TooMany errors.
+type LimitError struct {
+	Err   error
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: limit is %d", e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// exceeds reports whether n is over limit, treating a zero limit as none.
+func exceeds(n, limit int) bool {
+	return limit > 0 && n > limit
+}
@@ -0,0 +1,74 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readWithLimits(data string, limits Limits) (*Response, error) {
+	reader := NewReader(&chunkReader{data: data, numBytesPerRead: 5})
+	reader.Limits = limits
+	return reader.ReadResponse("GET")
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{
+		MaxStatusLineBytes: 32,
+		MaxHeaderBytes:     64,
+		MaxHeaderCount:     3,
+		MaxBodyBytes:       10,
+	}
+
+	// Test: Response inside every limit
+	resp, err := readWithLimits("HTTP/1.1 200 OK\r\nServer: test\r\nContent-Length: 10\r\n\r\n0123456789", limits)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(resp.Body))
+
+	// Test: Status line without a CRLF
+	_, err = readWithLimits("HTTP/1.1 200 "+strings.Repeat("a", 100), limits)
+	assert.ErrorIs(t, err, ErrStatusLineTooLong)
+	var limitErr *LimitError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 32, limitErr.Limit)
+
+	// Test: Header line without a CRLF
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\nX-Long: "+strings.Repeat("a", 100), limits)
+	assert.ErrorIs(t, err, ErrHeadersTooLarge)
+
+	// Test: Too many header fields
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\nA: 1\r\nB: 2\r\nC: 3\r\nD: 4\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrTooManyHeaders)
+
+	// Test: Trailers count against the header limits too
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrTooManyHeaders)
+
+	// Test: Declared body over the limit is rejected before it is read
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\nContent-Length: 11\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: Chunked and close-delimited bodies are bounded as they arrive
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n8\r\n01234567\r\n8\r\n01234567\r\n0\r\n\r\n", limits)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\n\r\n"+strings.Repeat("a", 100), limits)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: A streamed body is not buffered, so it is not bounded
+	reader := NewReader(&chunkReader{data: "HTTP/1.1 200 OK\r\nContent-Length: 20\r\n\r\n01234567890123456789", numBytesPerRead: 5})
+	reader.Limits = limits
+	reader.StreamBody = true
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.BodyReader)
+	require.NoError(t, err)
+	assert.Len(t, body, 20)
+
+	// Test: Zero limits mean no limit
+	_, err = readWithLimits("HTTP/1.1 200 OK\r\nX-Long: "+strings.Repeat("a", 100)+"\r\nContent-Length: 11\r\n\r\n01234567890", Limits{})
+	assert.NoError(t, err)
+}
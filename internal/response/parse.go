@@ -0,0 +1,375 @@
+package response
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/chunked"
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+type parserState int
+
+const (
+	responseStateInitialized parserState = iota
+	responseStateParsingHeaders
+	responseStateParsingBody
+	responseStateParsingBodyUntilClose
+	responseStateParsingChunkSize
+	responseStateParsingChunkData
+	responseStateParsingChunkDataEnd
+	responseStateParsingTrailers
+	responseStateDone
+)
+
+var (
+	ErrInvalidStatusLine    = errors.New("invalid status line")
+	ErrInvalidStatusCode    = errors.New("invalid status code")
+	ErrUnsupportedVersion   = errors.New("unsupported HTTP version")
+	ErrInvalidContentLength = errors.New("invalid content-length")
+	// ErrBodyTooShort is returned when the stream ends before Content-Length
+	// bytes of body have been read.
+	ErrBodyTooShort = errors.New("body shorter than content-length")
+)
+
+type StatusLine struct {
+	HttpVersion  string
+	StatusCode   StatusCode
+	ReasonPhrase string
+}
+
+// Response is a parsed response, the counterpart of request.Request.
+type Response struct {
+	StatusLine StatusLine
+	Headers    *headers.Headers
+	// Body holds the whole body, unless the response was read with
+	// StreamBody set.
+	Body []byte
+	// BodyReader streams the body when the response was read with
+	// StreamBody set, and is nil otherwise. It stops at the end of this
+	// response's body.
+	BodyReader io.ReadCloser
+	// Trailers holds the trailer fields sent after a chunked body.
+	Trailers *headers.Headers
+	// Interim holds the 1xx responses that arrived before this one, when it
+	// was read with ReadFinalResponse.
+	Interim []*Response
+
+	state parserState
+	// requestMethod is the method of the request this response answers,
+	// which decides whether it can have a body.
+	requestMethod string
+	// contentLength is the declared body size, read from the headers once
+	// they are complete.
+	contentLength int
+	// chunkRemaining is the number of bytes left in the current chunk of a
+	// chunked body.
+	chunkRemaining int
+	// bodyRead counts the decoded body bytes consumed so far.
+	bodyRead int
+	// pending holds decoded body bytes not yet returned by BodyReader when
+	// streaming, in place of Body.
+	pending    []byte
+	streamBody bool
+	// headerBytes counts the header and trailer bytes consumed so far.
+	headerBytes int
+	limits      Limits
+	// untilClose is set when the body is delimited by the end of the
+	// connection, which then cannot carry another response.
+	untilClose bool
+}
+
+func newResponse(requestMethod string, limits Limits) *Response {
+	return &Response{
+		state:         responseStateInitialized,
+		requestMethod: requestMethod,
+		Headers:       headers.NewHeaders(),
+		Trailers:      headers.NewHeaders(),
+		limits:        limits,
+	}
+}
+
+// ResponseFromReader parses a single response from r with the default
+// limits, assuming it answers a request that allows a body, such as GET.
+// Use a Reader to parse responses to HEAD or CONNECT, several responses
+// from the same connection, or to change the limits.
+func ResponseFromReader(r io.Reader) (*Response, error) {
+	return NewReader(r).ReadResponse("")
+}
+
+// KeepAlive reports whether the connection can carry another response after
+// this one.
+func (r *Response) KeepAlive() bool {
+	if r.untilClose || r.Headers.HasToken("connection", "close") {
+		return false
+	}
+	if r.StatusLine.HttpVersion == "1.0" {
+		return r.Headers.HasToken("connection", "keep-alive")
+	}
+	return true
+}
+
+func parseStatusLine(data []byte) (StatusLine, int, error) {
+	crlfIndex := bytes.Index(data, []byte("\r\n"))
+	if crlfIndex == -1 {
+		// Not enough data to parse the status line
+		return StatusLine{}, 0, nil
+	}
+	line := string(data[:crlfIndex])
+
+	// The reason phrase may contain spaces, or be empty. Some servers also
+	// drop the space before an empty reason phrase, which is accepted.
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return StatusLine{}, 0, fmt.Errorf("%w: %q", ErrInvalidStatusLine, line)
+	}
+	httpVersion, ok := strings.CutPrefix(parts[0], "HTTP/")
+	if !ok || len(httpVersion) != 3 || !isDigit(httpVersion[0]) || httpVersion[1] != '.' || !isDigit(httpVersion[2]) {
+		return StatusLine{}, 0, fmt.Errorf("%w: %q", ErrInvalidStatusLine, line)
+	}
+	if httpVersion[0] != '1' {
+		return StatusLine{}, 0, fmt.Errorf("%w: %s", ErrUnsupportedVersion, httpVersion)
+	}
+	code := parts[1]
+	if len(code) != 3 || !isDigit(code[0]) || !isDigit(code[1]) || !isDigit(code[2]) || code[0] < '1' || code[0] > '5' {
+		return StatusLine{}, 0, fmt.Errorf("%w: %q", ErrInvalidStatusCode, code)
+	}
+	statusCode, _ := strconv.Atoi(code)
+	reasonPhrase := ""
+	if len(parts) == 3 {
+		reasonPhrase = parts[2]
+	}
+	for _, c := range []byte(reasonPhrase) {
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return StatusLine{}, 0, fmt.Errorf("%w: control character in reason phrase", ErrInvalidStatusLine)
+		}
+	}
+	return StatusLine{
+		HttpVersion:  httpVersion,
+		StatusCode:   StatusCode(statusCode),
+		ReasonPhrase: reasonPhrase,
+	}, crlfIndex + 2, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parse feeds data through the state machine until it is done or needs more
+// data, and returns the number of bytes consumed.
+func (r *Response) parse(data []byte) (int, error) {
+	totalParsed := 0
+	for r.state != responseStateDone {
+		n, err := r.parseSingle(data[totalParsed:])
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		totalParsed += n
+	}
+	return totalParsed, nil
+}
+
+func (r *Response) parseSingle(data []byte) (int, error) {
+	switch r.state {
+	case responseStateInitialized:
+		statusLine, offset, err := parseStatusLine(data)
+		if err != nil {
+			return 0, err
+		}
+		lineLength := offset - 2
+		if offset == 0 {
+			lineLength = len(data)
+		}
+		if exceeds(lineLength, r.limits.MaxStatusLineBytes) {
+			return 0, &LimitError{Err: ErrStatusLineTooLong, Limit: r.limits.MaxStatusLineBytes}
+		}
+		if offset == 0 {
+			// More data is needed to parse the status line
+			return 0, nil
+		}
+		r.StatusLine = statusLine
+		r.state = responseStateParsingHeaders
+		return offset, nil
+	case responseStateParsingHeaders:
+		offset, done, err := r.parseField(r.Headers, data)
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			if err := r.startBody(); err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	case responseStateParsingBody:
+		n := min(r.contentLength-r.bodyRead, len(data))
+		if err := r.appendBody(data[:n]); err != nil {
+			return 0, err
+		}
+		if r.bodyRead == r.contentLength {
+			r.state = responseStateDone
+		}
+		return n, nil
+	case responseStateParsingBodyUntilClose:
+		if err := r.appendBody(data); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	case responseStateParsingChunkSize:
+		size, n, err := chunked.ParseSize(data)
+		if err != nil || n == 0 {
+			return 0, err
+		}
+		if size == 0 {
+			r.state = responseStateParsingTrailers
+		} else {
+			r.chunkRemaining = size
+			r.state = responseStateParsingChunkData
+		}
+		return n, nil
+	case responseStateParsingChunkData:
+		n := min(r.chunkRemaining, len(data))
+		if err := r.appendBody(data[:n]); err != nil {
+			return 0, err
+		}
+		r.chunkRemaining -= n
+		if r.chunkRemaining == 0 {
+			r.state = responseStateParsingChunkDataEnd
+		}
+		return n, nil
+	case responseStateParsingChunkDataEnd:
+		n, err := chunked.ParseDataEnd(data)
+		if err != nil || n == 0 {
+			return 0, err
+		}
+		r.state = responseStateParsingChunkSize
+		return n, nil
+	case responseStateParsingTrailers:
+		n, done, err := r.parseField(r.Trailers, data)
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			r.state = responseStateDone
+		}
+		return n, nil
+	case responseStateDone:
+		return 0, fmt.Errorf("error: trying to read data in a done state")
+	}
+	return 0, fmt.Errorf("unknown parser state: %d", r.state)
+}
+
+// parseField parses one field line into h, or the empty line that ends the
+// section, while keeping the section inside the header limits.
+func (r *Response) parseField(h *headers.Headers, data []byte) (int, bool, error) {
+	offset, done, err := h.Parse(data)
+	if err != nil {
+		return 0, false, err
+	}
+	if offset == 0 {
+		if exceeds(r.headerBytes+len(data), r.limits.MaxHeaderBytes) {
+			return 0, false, &LimitError{Err: ErrHeadersTooLarge, Limit: r.limits.MaxHeaderBytes}
+		}
+		return 0, false, nil
+	}
+	r.headerBytes += offset
+	if exceeds(r.headerBytes, r.limits.MaxHeaderBytes) {
+		return 0, false, &LimitError{Err: ErrHeadersTooLarge, Limit: r.limits.MaxHeaderBytes}
+	}
+	if exceeds(r.Headers.Len()+r.Trailers.Len(), r.limits.MaxHeaderCount) {
+		return 0, false, &LimitError{Err: ErrTooManyHeaders, Limit: r.limits.MaxHeaderCount}
+	}
+	return offset, done, nil
+}
+
+// appendBody stores decoded body bytes where the caller will look for them.
+// Only a body kept in Body counts against MaxBodyBytes.
+func (r *Response) appendBody(p []byte) error {
+	if !r.streamBody && exceeds(r.bodyRead+len(p), r.limits.MaxBodyBytes) {
+		return &LimitError{Err: ErrBodyTooLarge, Limit: r.limits.MaxBodyBytes}
+	}
+	r.bodyRead += len(p)
+	if r.streamBody {
+		r.pending = append(r.pending, p...)
+	} else {
+		r.Body = append(r.Body, p...)
+	}
+	return nil
+}
+
+// hasBody reports whether the response can carry a body at all, whatever
+// its headers say (RFC 9112 §6.3). Responses to HEAD describe a body without
+// sending it, and a successful CONNECT turns the connection into a tunnel.
+func (r *Response) hasBody() bool {
+	statusCode := r.StatusLine.StatusCode
+	switch {
+	case statusCode < 200, statusCode == StatusNoContent, statusCode == StatusNotModified:
+		return false
+	case r.requestMethod == "HEAD":
+		return false
+	case r.requestMethod == "CONNECT" && statusCode < 300:
+		return false
+	}
+	return true
+}
+
+// startBody picks the body framing once the headers are complete, following
+// RFC 9112 §6.3: responses that cannot have a body end here, then chunked
+// framing wins, then Content-Length, and otherwise the body runs until the
+// connection closes.
+func (r *Response) startBody() error {
+	if !r.hasBody() {
+		r.state = responseStateDone
+		return nil
+	}
+	isChunked, hasTransferEncoding := chunked.IsChunked(r.Headers)
+	if isChunked {
+		r.state = responseStateParsingChunkSize
+		return nil
+	}
+	if hasTransferEncoding {
+		r.state = responseStateParsingBodyUntilClose
+		r.untilClose = true
+		return nil
+	}
+	value, ok := r.Headers.Get("content-length")
+	if !ok {
+		r.state = responseStateParsingBodyUntilClose
+		r.untilClose = true
+		return nil
+	}
+	contentLength, err := strconv.Atoi(value)
+	if err != nil || strings.TrimLeft(value, "0123456789") != "" {
+		return fmt.Errorf("%w: %q", ErrInvalidContentLength, value)
+	}
+	if !r.streamBody && exceeds(contentLength, r.limits.MaxBodyBytes) {
+		return &LimitError{Err: ErrBodyTooLarge, Limit: r.limits.MaxBodyBytes}
+	}
+	r.contentLength = contentLength
+	if contentLength == 0 {
+		r.state = responseStateDone
+	} else {
+		r.state = responseStateParsingBody
+	}
+	return nil
+}
+
+// finishAtEOF handles the end of the stream: it completes a body read until
+// close, and explains why any other unfinished response is incomplete.
+func (r *Response) finishAtEOF() error {
+	switch r.state {
+	case responseStateParsingBodyUntilClose:
+		r.state = responseStateDone
+		return nil
+	case responseStateParsingBody:
+		return fmt.Errorf("%w: got %d of %d bytes", ErrBodyTooShort, r.bodyRead, r.contentLength)
+	}
+	return io.ErrUnexpectedEOF
+}
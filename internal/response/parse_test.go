@@ -0,0 +1,241 @@
+package response
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkReader struct {
+	data            string
+	numBytesPerRead int
+	pos             int
+}
+
+// Read reads up to len(p) or numBytesPerRead bytes from the string per call
+// its useful for simulating reading a variable number of bytes per chunk from a network connection
+func (cr *chunkReader) Read(p []byte) (n int, err error) {
+	if cr.pos >= len(cr.data) {
+		return 0, io.EOF
+	}
+	endIndex := min(cr.pos+cr.numBytesPerRead, len(cr.data))
+	n = copy(p, cr.data[cr.pos:endIndex])
+	cr.pos += n
+	return n, nil
+}
+
+func TestReadResponse(t *testing.T) {
+	// Test: Content-Length body
+	reader := NewReader(&chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nContent-Length: 5\r\nContent-Type: text/plain\r\n\r\nhello",
+		numBytesPerRead: 3,
+	})
+	resp, err := reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", resp.StatusLine.HttpVersion)
+	assert.Equal(t, StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "OK", resp.StatusLine.ReasonPhrase)
+	assert.Equal(t, []string{"text/plain"}, resp.Headers.Values("content-type"))
+	assert.Equal(t, "hello", string(resp.Body))
+	assert.True(t, resp.KeepAlive())
+
+	// Test: Chunked body with trailers
+	reader = NewReader(&chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n3\r\nabc\r\n2;x=y\r\nde\r\n0\r\nX-Sum: 5\r\n\r\n",
+		numBytesPerRead: 2,
+	})
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, "abcde", string(resp.Body))
+	assert.Equal(t, []string{"5"}, resp.Trailers.Values("x-sum"))
+
+	// Test: Body read until the connection closes
+	reader = NewReader(&chunkReader{
+		data:            "HTTP/1.0 200 OK\r\n\r\nall of the rest",
+		numBytesPerRead: 4,
+	})
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, "all of the rest", string(resp.Body))
+	assert.False(t, resp.KeepAlive())
+
+	// Test: A body read until close ends the connection in HTTP/1.1 too
+	reader = NewReader(&chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nall of the rest",
+		numBytesPerRead: 4,
+	})
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, "all of the rest", string(resp.Body))
+	assert.False(t, resp.KeepAlive())
+
+	// Test: Reason phrase with spaces, then a clean end of stream
+	reader = NewReader(&chunkReader{
+		data:            "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n",
+		numBytesPerRead: 5,
+	})
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotFound, resp.StatusLine.StatusCode)
+	assert.Equal(t, "Not Found", resp.StatusLine.ReasonPhrase)
+	_, err = reader.ReadResponse("GET")
+	assert.Equal(t, io.EOF, err)
+
+	// Test: Body shorter than Content-Length
+	reader = NewReader(&chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort",
+		numBytesPerRead: 3,
+	})
+	_, err = reader.ReadResponse("GET")
+	assert.ErrorIs(t, err, ErrBodyTooShort)
+
+	// Test: Garbage status line
+	reader = NewReader(&chunkReader{
+		data:            "SIP/2.0 200 OK\r\n\r\n",
+		numBytesPerRead: 3,
+	})
+	_, err = reader.ReadResponse("GET")
+	assert.ErrorIs(t, err, ErrInvalidStatusLine)
+}
+
+func TestReadFinalResponse(t *testing.T) {
+	reader := NewReader(&chunkReader{
+		data: "HTTP/1.1 100 Continue\r\n\r\n" +
+			"HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n" +
+			"HTTP/1.1 201 Created\r\nContent-Length: 2\r\n\r\nok",
+		numBytesPerRead: 7,
+	})
+
+	// Test: Interim responses are collected before the final one
+	resp, err := reader.ReadFinalResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCreated, resp.StatusLine.StatusCode)
+	assert.Equal(t, "ok", string(resp.Body))
+	require.Len(t, resp.Interim, 2)
+	assert.Equal(t, StatusContinue, resp.Interim[0].StatusLine.StatusCode)
+	assert.Equal(t, StatusCode(103), resp.Interim[1].StatusLine.StatusCode)
+	assert.Equal(t, []string{"</style.css>; rel=preload"}, resp.Interim[1].Headers.Values("link"))
+}
+
+func TestResponseFromReader(t *testing.T) {
+	// Test: Single response
+	resp, err := ResponseFromReader(&chunkReader{
+		data:            "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nhi",
+		numBytesPerRead: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(resp.Body))
+
+	// Test: Empty reason phrase, with and without the space
+	for _, line := range []string{"HTTP/1.1 299 \r\n", "HTTP/1.1 299\r\n"} {
+		resp, err = ResponseFromReader(&chunkReader{data: line + "Content-Length: 0\r\n\r\n", numBytesPerRead: 3})
+		require.NoError(t, err, line)
+		assert.Equal(t, StatusCode(299), resp.StatusLine.StatusCode)
+		assert.Equal(t, "", resp.StatusLine.ReasonPhrase)
+	}
+
+	// Test: Invalid status codes
+	for _, code := range []string{"20", "2000", "abc", "600", "099", "+20"} {
+		_, err = ResponseFromReader(&chunkReader{data: "HTTP/1.1 " + code + " OK\r\n\r\n", numBytesPerRead: 3})
+		assert.ErrorIs(t, err, ErrInvalidStatusCode, code)
+	}
+
+	// Test: Invalid and unsupported versions
+	for _, version := range []string{"HTTP/1", "HTTP/1.10", "HTTP/x.y", "http/1.1"} {
+		_, err = ResponseFromReader(&chunkReader{data: version + " 200 OK\r\n\r\n", numBytesPerRead: 3})
+		assert.ErrorIs(t, err, ErrInvalidStatusLine, version)
+	}
+	_, err = ResponseFromReader(&chunkReader{data: "HTTP/2.0 200 OK\r\n\r\n", numBytesPerRead: 3})
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	// Test: Control character in the reason phrase
+	_, err = ResponseFromReader(&chunkReader{data: "HTTP/1.1 200 O\x00K\r\n\r\n", numBytesPerRead: 3})
+	assert.ErrorIs(t, err, ErrInvalidStatusLine)
+}
+
+func TestResponsesWithoutBody(t *testing.T) {
+	// Test: HEAD, 204 and 304 responses end after the headers, even with
+	// framing headers that describe a body
+	reader := NewReader(&chunkReader{
+		data: "HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\n" +
+			"HTTP/1.1 204 No Content\r\nTransfer-Encoding: chunked\r\n\r\n" +
+			"HTTP/1.1 304 Not Modified\r\nContent-Length: 42\r\n\r\n" +
+			"HTTP/1.1 200 Connection Established\r\n\r\n",
+		numBytesPerRead: 5,
+	})
+	resp, err := reader.ReadResponse("HEAD")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Body)
+	assert.True(t, resp.KeepAlive())
+
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, StatusNoContent, resp.StatusLine.StatusCode)
+	assert.Empty(t, resp.Body)
+
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotModified, resp.StatusLine.StatusCode)
+	assert.Empty(t, resp.Body)
+
+	// Test: A successful CONNECT has no body, the tunnel starts right away
+	resp, err = reader.ReadResponse("CONNECT")
+	require.NoError(t, err)
+	assert.Empty(t, resp.Body)
+	_, err = reader.ReadResponse("GET")
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderStreamBody(t *testing.T) {
+	reader := NewReader(&chunkReader{
+		data: "HTTP/1.1 200 OK\r\nContent-Length: 11\r\n\r\nhello world" +
+			"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\nX-Sum: 1\r\n\r\n" +
+			"HTTP/1.1 204 No Content\r\n\r\n" +
+			"HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nabc" +
+			"HTTP/1.1 200 OK\r\n\r\nuntil close",
+		numBytesPerRead: 4,
+	})
+	reader.StreamBody = true
+
+	// Test: Content-Length body is streamed
+	resp, err := reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Nil(t, resp.Body)
+	require.NotNil(t, resp.BodyReader)
+	body, err := io.ReadAll(resp.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	// Test: Chunked body is streamed and trailers are parsed at the end
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(body))
+	sum, _ := resp.Trailers.Get("X-Sum")
+	assert.Equal(t, "1", sum)
+
+	// Test: Response without a body
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, StatusNoContent, resp.StatusLine.StatusCode)
+	body, err = io.ReadAll(resp.BodyReader)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+
+	// Test: Unread body is discarded before the next response
+	_, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+
+	// Test: Body read until the connection closes
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "until close", string(body))
+	assert.False(t, resp.KeepAlive())
+	_, err = reader.ReadResponse("GET")
+	assert.ErrorIs(t, err, io.EOF)
+}
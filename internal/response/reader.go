@@ -0,0 +1,129 @@
+package response
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/stream"
+)
+
+// Reader parses successive responses from one stream, keeping bytes read
+// past the end of one response for the next.
+type Reader struct {
+	// StreamBody makes ReadResponse return as soon as the headers are
+	// parsed, leaving the body to be read from the response's BodyReader.
+	StreamBody bool
+	// Limits applies to every response read after it is set.
+	Limits Limits
+
+	buf stream.Buffer
+	// body is the BodyReader of the last response read with StreamBody set.
+	body *stream.BodyReader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		buf:    stream.NewBuffer(r),
+		Limits: DefaultLimits,
+	}
+}
+
+// ReadResponse parses the next response, which may be a 1xx interim
+// response. requestMethod is the method of the request it answers, which
+// decides whether the response has a body. It returns io.EOF if the stream
+// ends cleanly before a new response starts. Any unread body of the
+// previous response is discarded first.
+func (rr *Reader) ReadResponse(requestMethod string) (*Response, error) {
+	if rr.body != nil {
+		if err := rr.body.Close(); err != nil {
+			return nil, err
+		}
+		rr.body = nil
+	}
+	resp := newResponse(requestMethod, rr.Limits)
+	resp.streamBody = rr.StreamBody
+	err := rr.parseUntil(resp, func() bool {
+		return resp.state == responseStateDone ||
+			(resp.streamBody && resp.state > responseStateParsingHeaders)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.streamBody {
+		rr.body = stream.NewBodyReader(streamedBody{reader: rr, resp: resp})
+		resp.BodyReader = rr.body
+	}
+	return resp, nil
+}
+
+// parseUntil feeds buffered bytes to resp, reading more from the stream
+// whenever the buffer runs dry, until stop reports true or the stream ends.
+// The buffer goes back to the pool once a response is done and nothing is
+// left in it.
+func (rr *Reader) parseUntil(resp *Response, stop func() bool) error {
+	parse := func(data []byte) (int, error) {
+		n, err := resp.parse(data)
+		if err != nil {
+			return 0, fmt.Errorf("error parsing response: %w", err)
+		}
+		return n, nil
+	}
+	done := func() bool {
+		if !stop() {
+			return false
+		}
+		if rr.buf.Buffered() == 0 && resp.state == responseStateDone {
+			rr.buf.Release()
+		}
+		return true
+	}
+	return rr.buf.ParseUntil(parse, done, func() error {
+		if resp.state == responseStateInitialized && rr.buf.Buffered() == 0 {
+			return io.EOF
+		}
+		if err := resp.finishAtEOF(); err != nil {
+			return fmt.Errorf("error parsing response: %w", err)
+		}
+		return nil
+	})
+}
+
+// ReadFinalResponse reads past any 1xx interim responses, keeping them in
+// the final response's Interim field. 101 Switching Protocols counts as
+// final, since the connection stops speaking HTTP after it.
+func (rr *Reader) ReadFinalResponse(requestMethod string) (*Response, error) {
+	var interim []*Response
+	for {
+		resp, err := rr.ReadResponse(requestMethod)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusLine.StatusCode >= 200 || resp.StatusLine.StatusCode == StatusSwitchingProtocols {
+			resp.Interim = interim
+			return resp, nil
+		}
+		interim = append(interim, resp)
+	}
+}
+
+// streamedBody lets a stream.BodyReader pull the body of resp through rr.
+type streamedBody struct {
+	reader *Reader
+	resp   *Response
+}
+
+func (s streamedBody) TakeBody() []byte {
+	p := s.resp.pending
+	s.resp.pending = s.resp.pending[:0]
+	return p
+}
+
+func (s streamedBody) ParseBody() error {
+	return s.reader.parseUntil(s.resp, func() bool {
+		return len(s.resp.pending) > 0 || s.resp.state == responseStateDone
+	})
+}
+
+func (s streamedBody) Done() bool {
+	return s.resp.state == responseStateDone
+}
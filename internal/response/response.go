@@ -0,0 +1,107 @@
+package response
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+type StatusCode int
+
+const (
+	StatusContinue                    StatusCode = 100
+	StatusSwitchingProtocols          StatusCode = 101
+	StatusOK                          StatusCode = 200
+	StatusCreated                     StatusCode = 201
+	StatusNoContent                   StatusCode = 204
+	StatusPartialContent              StatusCode = 206
+	StatusMovedPermanently            StatusCode = 301
+	StatusFound                       StatusCode = 302
+	StatusNotModified                 StatusCode = 304
+	StatusBadRequest                  StatusCode = 400
+	StatusForbidden                   StatusCode = 403
+	StatusNotFound                    StatusCode = 404
+	StatusMethodNotAllowed            StatusCode = 405
+	StatusRequestTimeout              StatusCode = 408
+	StatusLengthRequired              StatusCode = 411
+	StatusContentTooLarge             StatusCode = 413
+	StatusURITooLong                  StatusCode = 414
+	StatusRangeNotSatisfiable         StatusCode = 416
+	StatusExpectationFailed           StatusCode = 417
+	StatusRequestHeaderFieldsTooLarge StatusCode = 431
+	StatusInternalServerError         StatusCode = 500
+	StatusNotImplemented              StatusCode = 501
+	StatusBadGateway                  StatusCode = 502
+	StatusServiceUnavailable          StatusCode = 503
+	StatusGatewayTimeout              StatusCode = 504
+	StatusHTTPVersionNotSupported     StatusCode = 505
+)
+
+var reasonPhrases = map[StatusCode]string{
+	StatusContinue:                    "Continue",
+	StatusSwitchingProtocols:          "Switching Protocols",
+	StatusOK:                          "OK",
+	StatusCreated:                     "Created",
+	StatusNoContent:                   "No Content",
+	StatusPartialContent:              "Partial Content",
+	StatusMovedPermanently:            "Moved Permanently",
+	StatusFound:                       "Found",
+	StatusNotModified:                 "Not Modified",
+	StatusBadRequest:                  "Bad Request",
+	StatusForbidden:                   "Forbidden",
+	StatusNotFound:                    "Not Found",
+	StatusMethodNotAllowed:            "Method Not Allowed",
+	StatusRequestTimeout:              "Request Timeout",
+	StatusLengthRequired:              "Length Required",
+	StatusContentTooLarge:             "Content Too Large",
+	StatusURITooLong:                  "URI Too Long",
+	StatusRangeNotSatisfiable:         "Range Not Satisfiable",
+	StatusExpectationFailed:           "Expectation Failed",
+	StatusRequestHeaderFieldsTooLarge: "Request Header Fields Too Large",
+	StatusInternalServerError:         "Internal Server Error",
+	StatusNotImplemented:              "Not Implemented",
+	StatusBadGateway:                  "Bad Gateway",
+	StatusServiceUnavailable:          "Service Unavailable",
+	StatusGatewayTimeout:              "Gateway Timeout",
+	StatusHTTPVersionNotSupported:     "HTTP Version Not Supported",
+}
+
+// ReasonPhrase returns the standard reason phrase for a status code, or an
+// empty string if the code is not known.
+func ReasonPhrase(statusCode StatusCode) string {
+	return reasonPhrases[statusCode]
+}
+
+// WriteStatusLine writes an HTTP/1.1 status line such as
+// "HTTP/1.1 200 OK\r\n". Unknown codes are written with an empty reason
+// phrase, which the grammar allows.
+func WriteStatusLine(w io.Writer, statusCode StatusCode) error {
+	if statusCode < 100 || statusCode > 999 {
+		return fmt.Errorf("invalid status code: %d", statusCode)
+	}
+	_, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", statusCode, ReasonPhrase(statusCode))
+	return err
+}
+
+// GetDefaultHeaders returns the headers every response starts from: the body
+// size, a plain-text content type, and a persistent connection. A Writer
+// switches the connection to close when it cannot be kept open.
+func GetDefaultHeaders(contentLen int) *headers.Headers {
+	h := headers.NewHeaders()
+	h.Set("Content-Length", strconv.Itoa(contentLen))
+	h.Set("Connection", "keep-alive")
+	h.Set("Content-Type", "text/plain")
+	return h
+}
+
+// WriteHeaders writes each header as a field line, in order and with its
+// original casing, followed by the empty line that ends the header section.
+func WriteHeaders(w io.Writer, headers *headers.Headers) error {
+	if _, err := headers.WriteTo(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
@@ -0,0 +1,238 @@
+package response
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteStatusLine(t *testing.T) {
+	// Test: Known status code
+	var buf bytes.Buffer
+	err := WriteStatusLine(&buf, StatusOK)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", buf.String())
+
+	// Test: Unknown status code keeps an empty reason phrase
+	buf.Reset()
+	err = WriteStatusLine(&buf, 299)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 299 \r\n", buf.String())
+
+	// Test: Out of range status code
+	buf.Reset()
+	err = WriteStatusLine(&buf, 42)
+	require.Error(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestWriteHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteHeaders(&buf, GetDefaultHeaders(13))
+	require.NoError(t, err)
+	assert.Equal(t, "Content-Length: 13\r\nConnection: keep-alive\r\nContent-Type: text/plain\r\n\r\n", buf.String())
+}
+
+func TestWriter(t *testing.T) {
+	// Test: Status line, headers and body in order
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusNotFound))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(9)))
+	n, err := w.WriteBody([]byte("not found"))
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+	assert.Equal(t, "HTTP/1.1 404 Not Found\r\n"+
+		"Content-Length: 9\r\nConnection: close\r\nContent-Type: text/plain\r\n\r\n"+
+		"not found", buf.String())
+
+	// Test: Headers before the status line
+	buf.Reset()
+	w = NewWriter(&buf)
+	err = w.WriteHeaders(GetDefaultHeaders(0))
+	assert.ErrorIs(t, err, ErrWriteOrder)
+
+	// Test: Body before the headers
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	_, err = w.WriteBody([]byte("early"))
+	assert.ErrorIs(t, err, ErrWriteOrder)
+
+	// Test: Status line written twice
+	err = w.WriteStatusLine(StatusOK)
+	assert.ErrorIs(t, err, ErrWriteOrder)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", buf.String())
+}
+
+func TestWriterKeepAlive(t *testing.T) {
+	// Test: Keep-alive with a known length
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetKeepAlive(true)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+	assert.True(t, w.KeepAlive())
+	assert.Contains(t, buf.String(), "Connection: keep-alive\r\n")
+
+	// Test: No Content-Length means a chunked body, kept alive once ended
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetKeepAlive(true)
+	h := GetDefaultHeaders(0)
+	h.Del("content-length")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	assert.Contains(t, buf.String(), "Transfer-Encoding: chunked\r\n")
+	assert.False(t, w.KeepAlive())
+	_, err := w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	assert.True(t, w.KeepAlive())
+
+	// Test: HTTP/1.0 clients get no chunking, so the connection must close
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetKeepAlive(true)
+	w.SetRequestVersion("1.0")
+	h = GetDefaultHeaders(0)
+	h.Del("content-length")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	assert.False(t, w.Chunked())
+	assert.False(t, w.KeepAlive())
+	assert.Contains(t, buf.String(), "Connection: close\r\n")
+	assert.NotContains(t, buf.String(), "Transfer-Encoding")
+
+	// Test: 204 needs no Content-Length
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetKeepAlive(true)
+	h = GetDefaultHeaders(0)
+	h.Del("content-length")
+	require.NoError(t, w.WriteStatusLine(StatusNoContent))
+	require.NoError(t, w.WriteHeaders(h))
+	assert.True(t, w.KeepAlive())
+
+	// Test: Handler asks to close
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetKeepAlive(true)
+	h = GetDefaultHeaders(0)
+	h.Set("Connection", "close")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	assert.False(t, w.KeepAlive())
+
+	// Test: Writers close unless told otherwise
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(0)))
+	assert.False(t, w.KeepAlive())
+	assert.Contains(t, buf.String(), "Connection: close\r\n")
+
+	// Test: Fields added for one response do not leak into the next
+	shared := GetDefaultHeaders(0)
+	shared.Del("content-length")
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(shared))
+	assert.Contains(t, buf.String(), "Connection: close\r\n")
+	connection, _ := shared.Get("connection")
+	assert.Equal(t, "keep-alive", connection)
+	_, ok := shared.Get("transfer-encoding")
+	assert.False(t, ok)
+}
+
+func TestWriterChunked(t *testing.T) {
+	// Test: Chunks and the last chunk
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetKeepAlive(true)
+	h := headers.NewHeaders()
+	h.Set("Content-Type", "text/plain")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	require.True(t, w.Chunked())
+	n, err := w.WriteChunkedBody([]byte("hello "))
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	_, err = w.WriteChunkedBody(nil)
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBody([]byte("chunked world"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Content-Type: text/plain\r\nTransfer-Encoding: chunked\r\n\r\n"+
+		"6\r\nhello \r\nd\r\nchunked world\r\n0\r\n\r\n", buf.String())
+
+	// Test: WriteBody sends chunks too when the length is not known
+	buf.Reset()
+	w = NewWriter(&buf)
+	h = GetDefaultHeaders(0)
+	h.Del("content-length")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	n, err = w.WriteBody([]byte("hello "))
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	_, err = w.WriteBody([]byte("world"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	resp, err := ResponseFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(resp.Body))
+
+	// Test: Writing after the body ended
+	_, err = w.WriteChunkedBody([]byte("late"))
+	assert.ErrorIs(t, err, ErrWriteOrder)
+	assert.ErrorIs(t, w.WriteTrailers(headers.NewHeaders()), ErrWriteOrder)
+
+	// Test: Chunked writes need chunked headers
+	buf.Reset()
+	w = NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(GetDefaultHeaders(4)))
+	_, err = w.WriteChunkedBody([]byte("body"))
+	assert.ErrorIs(t, err, ErrNotChunked)
+}
+
+func TestWriterTrailers(t *testing.T) {
+	// Test: Checksum computed while streaming is sent as a trailer
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetKeepAlive(true)
+	h := headers.NewHeaders()
+	h.Set("Trailer", "X-Content-SHA256")
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(h))
+	sum := sha256.New()
+	for _, part := range []string{"stream", "ed ", "report"} {
+		_, err := w.WriteChunkedBody([]byte(part))
+		require.NoError(t, err)
+		sum.Write([]byte(part))
+	}
+	_, err := w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	assert.False(t, w.KeepAlive())
+	trailers := headers.NewHeaders()
+	trailers.Set("X-Content-SHA256", hex.EncodeToString(sum.Sum(nil)))
+	require.NoError(t, w.WriteTrailers(trailers))
+	assert.True(t, w.KeepAlive())
+
+	resp, err := ResponseFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed report", string(resp.Body))
+	want := sha256.Sum256([]byte("streamed report"))
+	got, ok := resp.Trailers.Get("X-Content-SHA256")
+	assert.True(t, ok)
+	assert.Equal(t, hex.EncodeToString(want[:]), got)
+	assert.Equal(t, 0, buf.Len())
+}
@@ -0,0 +1,199 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/chunked"
+	"github.com/madhu1992blue/httpfromtcp/internal/headers"
+)
+
+type writerState int
+
+const (
+	writerStateStatusLine writerState = iota
+	writerStateHeaders
+	writerStateBody
+	writerStateTrailers
+	writerStateDone
+)
+
+var (
+	// ErrWriteOrder is returned when a part of the response is written out
+	// of order: the status line first, then the headers, then the body and,
+	// for a chunked body, the trailers.
+	ErrWriteOrder = errors.New("response written out of order")
+	// ErrNotChunked is returned when chunked body data is written to a
+	// response whose headers did not choose the chunked transfer coding.
+	ErrNotChunked = errors.New("response body is not chunked")
+)
+
+// Writer writes a response to a connection, making sure the status line,
+// headers and body go out in that order.
+type Writer struct {
+	writer      io.Writer
+	writerState writerState
+	statusCode  StatusCode
+	keepAlive   bool
+	// noChunked is set for HTTP/1.0 clients, which do not understand the
+	// chunked transfer coding.
+	noChunked bool
+	// chunked is set when the body is sent with the chunked transfer coding.
+	chunked bool
+	// trailers is set when the headers announced trailer fields with a
+	// Trailer header, so the chunked body ends with WriteTrailers.
+	trailers bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		writer:      w,
+		writerState: writerStateStatusLine,
+	}
+}
+
+func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
+	if w.writerState != writerStateStatusLine {
+		return fmt.Errorf("%w: status line already written", ErrWriteOrder)
+	}
+	if err := WriteStatusLine(w.writer, statusCode); err != nil {
+		return err
+	}
+	w.statusCode = statusCode
+	w.writerState = writerStateHeaders
+	return nil
+}
+
+// WriteHeaders writes the headers. A response that can have a body but
+// gives no Content-Length is sent with "Transfer-Encoding: chunked": its body
+// is then written in chunks and ended with WriteChunkedBodyDone. The fields the writer
+// adds go on a copy, so headers can be reused for later responses.
+func (w *Writer) WriteHeaders(headers *headers.Headers) error {
+	if w.writerState != writerStateHeaders {
+		return fmt.Errorf("%w: headers must follow the status line", ErrWriteOrder)
+	}
+	headers = headers.Clone()
+	w.chunked, _ = chunked.IsChunked(headers)
+	if !w.chunked && !w.noChunked && !hasKnownLength(w.statusCode, headers) {
+		headers.Set("Transfer-Encoding", "chunked")
+		w.chunked = true
+	}
+	_, w.trailers = headers.Get("trailer")
+	if !w.keepAlive || headers.HasToken("connection", "close") || !(w.chunked || hasKnownLength(w.statusCode, headers)) {
+		// The client can only find the end of the body, or learn that no
+		// more responses follow, by the connection closing.
+		w.keepAlive = false
+		headers.Set("Connection", "close")
+	}
+	if err := WriteHeaders(w.writer, headers); err != nil {
+		return err
+	}
+	w.writerState = writerStateBody
+	return nil
+}
+
+// SetKeepAlive tells the writer whether the client asked for the connection
+// to stay open after this response. Writers start out closing the connection.
+func (w *Writer) SetKeepAlive(keepAlive bool) {
+	w.keepAlive = keepAlive
+}
+
+// SetRequestVersion tells the writer the HTTP version of the request being
+// answered, such as "1.0". Bodies of unknown length are only chunked for
+// HTTP/1.1 clients; HTTP/1.0 ones read them until the connection closes.
+func (w *Writer) SetRequestVersion(version string) {
+	w.noChunked = version == "1.0"
+}
+
+// KeepAlive reports whether the connection can carry another response after
+// this one. It is false until the headers are written, and for a chunked
+// body until the body has been ended.
+func (w *Writer) KeepAlive() bool {
+	if w.chunked {
+		return w.keepAlive && w.writerState == writerStateDone
+	}
+	return w.keepAlive && w.writerState == writerStateBody
+}
+
+// Chunked reports whether the headers chose the chunked transfer coding.
+func (w *Writer) Chunked() bool {
+	return w.chunked
+}
+
+// hasKnownLength reports whether the client can tell where the body of a
+// response with these headers ends without the connection closing.
+func hasKnownLength(statusCode StatusCode, headers *headers.Headers) bool {
+	if statusCode < 200 || statusCode == StatusNoContent || statusCode == StatusNotModified {
+		return true
+	}
+	_, ok := headers.Get("content-length")
+	return ok
+}
+
+// WriteBody writes p as the next part of the body. If the headers chose the
+// chunked transfer coding, p is sent as one chunk, as with WriteChunkedBody,
+// so that the client does not lose track of where the body ends.
+func (w *Writer) WriteBody(p []byte) (int, error) {
+	if w.writerState != writerStateBody {
+		return 0, fmt.Errorf("%w: body must follow the headers", ErrWriteOrder)
+	}
+	if w.chunked {
+		return w.WriteChunkedBody(p)
+	}
+	return w.writer.Write(p)
+}
+
+// WriteChunkedBody writes p as one chunk of a chunked body. An empty p
+// writes nothing, since a zero-size chunk would end the body.
+func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
+	if w.writerState != writerStateBody {
+		return 0, fmt.Errorf("%w: body must follow the headers", ErrWriteOrder)
+	}
+	if !w.chunked {
+		return 0, ErrNotChunked
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	chunk := make([]byte, 0, len(p)+20)
+	chunk = fmt.Appendf(chunk, "%x\r\n", len(p))
+	chunk = append(chunk, p...)
+	chunk = append(chunk, "\r\n"...)
+	if _, err := w.writer.Write(chunk); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteChunkedBodyDone ends a chunked body with the last, zero-size chunk.
+// If the headers announced trailer fields, the response is completed by
+// WriteTrailers; otherwise it is complete now.
+func (w *Writer) WriteChunkedBodyDone() (int, error) {
+	if w.writerState != writerStateBody {
+		return 0, fmt.Errorf("%w: body must follow the headers", ErrWriteOrder)
+	}
+	if !w.chunked {
+		return 0, ErrNotChunked
+	}
+	if w.trailers {
+		w.writerState = writerStateTrailers
+		return w.writer.Write([]byte("0\r\n"))
+	}
+	w.writerState = writerStateDone
+	return w.writer.Write([]byte("0\r\n\r\n"))
+}
+
+// WriteTrailers writes the trailer fields after a chunked body, such as a
+// checksum computed while the body was streamed, and completes the
+// response. The fields should be named in the Trailer header.
+func (w *Writer) WriteTrailers(trailers *headers.Headers) error {
+	if w.writerState != writerStateTrailers {
+		return fmt.Errorf("%w: trailers must follow a chunked body announcing them", ErrWriteOrder)
+	}
+	if err := WriteHeaders(w.writer, trailers); err != nil {
+		return err
+	}
+	w.writerState = writerStateDone
+	return nil
+}
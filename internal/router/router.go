@@ -0,0 +1,185 @@
+package router
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/madhu1992blue/httpfromtcp/internal/server"
+)
+
+// Router sends each request to the handler registered for its method and
+// path. Patterns are paths whose segments may be:
+//
+//   - a literal, such as "users", matching itself
+//   - "{name}", matching any one segment and storing it as a path value
+//   - "*", as the last segment only, matching the rest of the path, which is
+//     stored as the path value "*"
+//
+// When several patterns match, the one with literals furthest to the left
+// wins, so "/users/me" is preferred over "/users/{id}".
+type Router struct {
+	routes []route
+}
+
+type route struct {
+	method   string
+	segments []string
+	handler  server.Handler
+}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers handler for requests with the given method and a path
+// matching pattern. It panics on a malformed pattern, since that is a
+// programming error.
+func (rt *Router) Handle(method, pattern string, handler server.Handler) {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	rt.routes = append(rt.routes, route{
+		method:   method,
+		segments: segments,
+		handler:  handler,
+	})
+}
+
+// ServeHTTP dispatches req. It answers 404 when no pattern matches the path,
+// and 405 with an Allow header when patterns match but none for the method.
+// It has the shape of a server.Handler.
+func (rt *Router) ServeHTTP(w *response.Writer, req *request.Request) {
+	// Splitting before decoding keeps an encoded "/" from starting a new
+	// segment, which would let a path value pose as more of the path.
+	segments := req.RequestLine.Target.PathSegments()
+	if segments == nil {
+		writeStatus(w, response.StatusNotFound, "")
+		return
+	}
+
+	var best *route
+	var bestValues map[string]string
+	var allowed []string
+	for i := range rt.routes {
+		r := &rt.routes[i]
+		values, ok := match(r.segments, segments)
+		if !ok {
+			continue
+		}
+		if !slices.Contains(allowed, r.method) {
+			allowed = append(allowed, r.method)
+		}
+		if r.method != req.RequestLine.Method {
+			continue
+		}
+		if best == nil || moreSpecific(r.segments, best.segments) {
+			best = r
+			bestValues = values
+		}
+	}
+	if best == nil {
+		if len(allowed) == 0 {
+			writeStatus(w, response.StatusNotFound, "")
+			return
+		}
+		slices.Sort(allowed)
+		writeStatus(w, response.StatusMethodNotAllowed, strings.Join(allowed, ", "))
+		return
+	}
+	for name, value := range bestValues {
+		req.SetPathValue(name, value)
+	}
+	best.handler(w, req)
+}
+
+func parsePattern(pattern string) ([]string, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("router: pattern %q must start with /", pattern)
+	}
+	segments := strings.Split(pattern[1:], "/")
+	for i, segment := range segments {
+		if segment == "*" && i != len(segments)-1 {
+			return nil, fmt.Errorf("router: wildcard must be the last segment in %q", pattern)
+		}
+		if strings.HasPrefix(segment, "{") != strings.HasSuffix(segment, "}") || segment == "{}" {
+			return nil, fmt.Errorf("router: malformed parameter %q in %q", segment, pattern)
+		}
+	}
+	return segments, nil
+}
+
+// match reports whether the path segments fit the pattern, and returns the
+// path values the pattern captures.
+func match(pattern, segments []string) (map[string]string, bool) {
+	values := map[string]string{}
+	for i, p := range pattern {
+		if p == "*" {
+			values["*"] = strings.Join(segments[i:], "/")
+			return values, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if isParam(p) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			values[p[1:len(p)-1]] = segments[i]
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	return values, true
+}
+
+// moreSpecific reports whether pattern a should win over b when both match:
+// at the first segment where they differ in kind, a literal beats a
+// parameter, which beats a wildcard.
+func moreSpecific(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if rank(a[i]) != rank(b[i]) {
+			return rank(a[i]) < rank(b[i])
+		}
+	}
+	return len(a) > len(b)
+}
+
+func rank(segment string) int {
+	switch {
+	case segment == "*":
+		return 2
+	case isParam(segment):
+		return 1
+	}
+	return 0
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// writeStatus answers with statusCode and its reason phrase as the body,
+// listing the allowed methods if there are any.
+func writeStatus(w *response.Writer, statusCode response.StatusCode, allow string) {
+	body := []byte(response.ReasonPhrase(statusCode) + "\n")
+	h := response.GetDefaultHeaders(len(body))
+	if allow != "" {
+		h.Set("Allow", allow)
+	}
+	if err := w.WriteStatusLine(statusCode); err != nil {
+		return
+	}
+	if err := w.WriteHeaders(h); err != nil {
+		return
+	}
+	w.WriteBody(body)
+}
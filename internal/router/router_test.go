@@ -0,0 +1,99 @@
+package router
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textHandler answers with name followed by the requested path values.
+func textHandler(name string, values ...string) func(w *response.Writer, req *request.Request) {
+	return func(w *response.Writer, req *request.Request) {
+		body := name
+		for _, value := range values {
+			body += " " + value + "=" + req.PathValue(value)
+		}
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody([]byte(body))
+	}
+}
+
+// serve routes a request line through rt and parses what it writes back.
+func serve(t *testing.T, rt *Router, method, target string) *response.Response {
+	t.Helper()
+	req, err := request.RequestFromReader(strings.NewReader(method + " " + target + " HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	rt.ServeHTTP(response.NewWriter(&buf), req)
+	resp, err := response.ResponseFromReader(&buf)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestRouter(t *testing.T) {
+	rt := NewRouter()
+	rt.Handle("GET", "/", textHandler("root"))
+	rt.Handle("GET", "/users/{id}", textHandler("user", "id"))
+	rt.Handle("DELETE", "/users/{id}", textHandler("delete user", "id"))
+	rt.Handle("GET", "/users/me", textHandler("me"))
+	rt.Handle("GET", "/users/{id}/posts/{post}", textHandler("post", "id", "post"))
+	rt.Handle("GET", "/static/*", textHandler("static", "*"))
+
+	// Test: Literal path
+	resp := serve(t, rt, "GET", "/")
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "root", string(resp.Body))
+
+	// Test: Path parameters
+	resp = serve(t, rt, "GET", "/users/42")
+	assert.Equal(t, "user id=42", string(resp.Body))
+	resp = serve(t, rt, "GET", "/users/42/posts/7?draft=1")
+	assert.Equal(t, "post id=42 post=7", string(resp.Body))
+
+	// Test: Literal beats parameter
+	resp = serve(t, rt, "GET", "/users/me")
+	assert.Equal(t, "me", string(resp.Body))
+
+	// Test: Parameters are percent-decoded
+	resp = serve(t, rt, "GET", "/users/j%20doe")
+	assert.Equal(t, "user id=j doe", string(resp.Body))
+
+	// Test: An encoded slash stays inside its parameter
+	resp = serve(t, rt, "GET", "/users/a%2Fb")
+	assert.Equal(t, "user id=a/b", string(resp.Body))
+	resp = serve(t, rt, "GET", "/users/42%2Fposts%2F7")
+	assert.Equal(t, "user id=42/posts/7", string(resp.Body))
+
+	// Test: Wildcard matches the rest of the path
+	resp = serve(t, rt, "GET", "/static/css/site.css")
+	assert.Equal(t, "static *=css/site.css", string(resp.Body))
+
+	// Test: Method selects between routes on the same pattern
+	resp = serve(t, rt, "DELETE", "/users/42")
+	assert.Equal(t, "delete user id=42", string(resp.Body))
+
+	// Test: Unknown path
+	resp = serve(t, rt, "GET", "/nope")
+	assert.Equal(t, response.StatusNotFound, resp.StatusLine.StatusCode)
+	resp = serve(t, rt, "GET", "/users/")
+	assert.Equal(t, response.StatusNotFound, resp.StatusLine.StatusCode)
+
+	// Test: Known path, wrong method
+	resp = serve(t, rt, "POST", "/users/42")
+	assert.Equal(t, response.StatusMethodNotAllowed, resp.StatusLine.StatusCode)
+	assert.Equal(t, []string{"DELETE, GET"}, resp.Headers.Values("allow"))
+}
+
+func TestBadPatterns(t *testing.T) {
+	rt := NewRouter()
+	assert.Panics(t, func() { rt.Handle("GET", "users", textHandler("x")) })
+	assert.Panics(t, func() { rt.Handle("GET", "/static/*/more", textHandler("x")) })
+	assert.Panics(t, func() { rt.Handle("GET", "/users/{id", textHandler("x")) })
+	assert.Panics(t, func() { rt.Handle("GET", "/users/{}", textHandler("x")) })
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+)
+
+// checkExpect decides how to answer the Expect header of an HTTP/1.1
+// request whose headers have just been read. It returns StatusContinue to
+// go ahead, or the status to reject the request with without reading its
+// body: 417 for an expectation other than 100-continue, or whatever the
+// ExpectContinue hook returned.
+func (s *Server) checkExpect(req *request.Request) (response.StatusCode, error) {
+	value, ok := req.Headers.Get("expect")
+	if !ok || req.RequestLine.HttpVersion == "1.0" {
+		return response.StatusContinue, nil
+	}
+	if !strings.EqualFold(strings.TrimSpace(value), "100-continue") {
+		return response.StatusExpectationFailed, fmt.Errorf("unsupported expectation: %q", value)
+	}
+	if s.ExpectContinue == nil || !req.ExpectsContinue() {
+		return response.StatusContinue, nil
+	}
+	if status := s.ExpectContinue(req); status != response.StatusContinue {
+		return status, errors.New(strings.ToLower(response.ReasonPhrase(status)))
+	}
+	return response.StatusContinue, nil
+}
+
+// continueReader wraps the body of a request sent with "Expect:
+// 100-continue", and sends the 100 Continue the client is waiting for right
+// before the body is first read. A body that is never read is never asked
+// for.
+type continueReader struct {
+	conn io.Writer
+	body io.ReadCloser
+	sent bool
+	err  error
+}
+
+func (c *continueReader) Read(p []byte) (int, error) {
+	if !c.sent {
+		c.sent = true
+		var buf bytes.Buffer
+		response.WriteStatusLine(&buf, response.StatusContinue)
+		buf.WriteString("\r\n")
+		if _, err := c.conn.Write(buf.Bytes()); err != nil {
+			c.err = err
+		}
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.body.Read(p)
+}
+
+// Close discards the rest of the body once the client has been asked for
+// it. Before that there is nothing to discard, and the connection has to
+// close instead, since the client may or may not send the body anyway.
+func (c *continueReader) Close() error {
+	if !c.sent {
+		return nil
+	}
+	return c.body.Close()
+}
+
+// rejectExpectation answers a request whose body will not be read, and
+// shuts the connection since the client may send that body regardless.
+func rejectExpectation(conn net.Conn, status response.StatusCode, err error) {
+	writeError(response.NewWriter(conn), status, err)
+	lingeringClose(conn)
+}
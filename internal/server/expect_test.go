@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+)
+
+// chunkConn is a net.Conn that hands out head a few bytes per read, like
+// chunkReader, and records everything written to it. Like a client that
+// sent "Expect: 100-continue", it holds back body until the server has
+// written a 100 Continue. The stream ends once it runs out of data.
+type chunkConn struct {
+	head            string
+	body            string
+	numBytesPerRead int
+	pos             int
+	written         bytes.Buffer
+}
+
+func (c *chunkConn) Read(p []byte) (int, error) {
+	data := c.head
+	if strings.Contains(c.written.String(), "HTTP/1.1 100 Continue\r\n\r\n") {
+		data += c.body
+	}
+	if c.pos >= len(data) {
+		return 0, io.EOF
+	}
+	end := min(c.pos+c.numBytesPerRead, len(data))
+	n := copy(p, data[c.pos:end])
+	c.pos += n
+	return n, nil
+}
+
+func (c *chunkConn) Write(p []byte) (int, error)        { return c.written.Write(p) }
+func (c *chunkConn) Close() error                       { return nil }
+func (c *chunkConn) LocalAddr() net.Addr                { return &net.TCPAddr{} }
+func (c *chunkConn) RemoteAddr() net.Addr               { return &net.TCPAddr{} }
+func (c *chunkConn) SetDeadline(t time.Time) error      { return nil }
+func (c *chunkConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *chunkConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// echoHandler answers with the request body.
+func echoHandler(w *response.Writer, req *request.Request) {
+	body := req.Body
+	if req.BodyReader != nil {
+		body, _ = io.ReadAll(req.BodyReader)
+	}
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+const expectHead = "POST /upload HTTP/1.1\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\n"
+
+func TestExpectContinue(t *testing.T) {
+	// Test: Body is asked for with 100 Continue before it is read
+	conn := &chunkConn{head: expectHead, body: "hello", numBytesPerRead: 3}
+	NewServer(echoHandler).handle(conn)
+	got := conn.written.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200 OK\r\n"), got)
+	assert.True(t, strings.HasSuffix(got, "\r\n\r\nhello"), got)
+
+	// Test: Hook accepting the body
+	s := NewServer(echoHandler)
+	s.ExpectContinue = func(req *request.Request) response.StatusCode {
+		return response.StatusContinue
+	}
+	conn = &chunkConn{head: expectHead, body: "hello", numBytesPerRead: 3}
+	s.handle(conn)
+	assert.Contains(t, conn.written.String(), "HTTP/1.1 100 Continue\r\n\r\n")
+	assert.True(t, strings.HasSuffix(conn.written.String(), "hello"))
+
+	// Test: Hook rejecting the body sees only the headers
+	called := false
+	s = NewServer(func(w *response.Writer, req *request.Request) {
+		called = true
+		echoHandler(w, req)
+	})
+	s.ExpectContinue = func(req *request.Request) response.StatusCode {
+		assert.Nil(t, req.Body)
+		length, _ := req.Headers.Get("content-length")
+		if length != "0" {
+			return response.StatusContentTooLarge
+		}
+		return response.StatusContinue
+	}
+	conn = &chunkConn{head: expectHead, body: "hello", numBytesPerRead: 3}
+	s.handle(conn)
+	got = conn.written.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 413 Content Too Large\r\n"), got)
+	assert.Contains(t, got, "Connection: close\r\n")
+	assert.NotContains(t, got, "100 Continue")
+	assert.False(t, called)
+
+	// Test: Unknown expectation
+	conn = &chunkConn{head: "POST / HTTP/1.1\r\nExpect: tea\r\nContent-Length: 5\r\n\r\n", body: "hello", numBytesPerRead: 3}
+	NewServer(echoHandler).handle(conn)
+	got = conn.written.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 417 Expectation Failed\r\n"), got)
+
+	// Test: No body means no 100 Continue
+	conn = &chunkConn{head: "POST / HTTP/1.1\r\nExpect: 100-continue\r\nContent-Length: 0\r\n\r\n", numBytesPerRead: 3}
+	NewServer(echoHandler).handle(conn)
+	assert.True(t, strings.HasPrefix(conn.written.String(), "HTTP/1.1 200 OK\r\n"))
+
+	// Test: HTTP/1.0 clients send the body right away and get no interim response
+	conn = &chunkConn{head: "POST / HTTP/1.0\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\nhello", numBytesPerRead: 3}
+	NewServer(echoHandler).handle(conn)
+	got = conn.written.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 200 OK\r\n"), got)
+	assert.True(t, strings.HasSuffix(got, "hello"))
+}
+
+func TestExpectContinueStreaming(t *testing.T) {
+	// Test: Streaming handler reading the body triggers 100 Continue
+	s := NewServer(echoHandler)
+	s.ParserOptions.StreamBody = true
+	conn := &chunkConn{head: expectHead, body: "hello", numBytesPerRead: 3}
+	s.handle(conn)
+	got := conn.written.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200 OK\r\n"), got)
+	assert.True(t, strings.HasSuffix(got, "hello"))
+
+	// Test: Streaming handler answering without reading the body
+	s = NewServer(func(w *response.Writer, req *request.Request) {
+		assert.True(t, req.ExpectsContinue())
+		body := []byte("no thanks")
+		w.SetKeepAlive(true)
+		w.WriteStatusLine(response.StatusContentTooLarge)
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody(body)
+	})
+	s.ParserOptions.StreamBody = true
+	conn = &chunkConn{
+		head:            expectHead + "GET /next HTTP/1.1\r\n\r\n",
+		body:            "hello",
+		numBytesPerRead: 3,
+	}
+	s.handle(conn)
+	got = conn.written.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 413 Content Too Large\r\n"), got)
+	assert.NotContains(t, got, "100 Continue")
+	// The connection closes rather than guess whether the body follows.
+	assert.Equal(t, 1, strings.Count(got, "HTTP/1.1 "))
+}
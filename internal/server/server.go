@@ -0,0 +1,326 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+)
+
+// Handler answers a single parsed request by writing a response to w.
+type Handler func(w *response.Writer, req *request.Request)
+
+// Default timeouts set by NewServer.
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 60 * time.Second
+	defaultWriteTimeout      = 120 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+)
+
+// errorWriteTimeout bounds writing an error response to a client that has
+// already been too slow.
+const errorWriteTimeout = 5 * time.Second
+
+type Server struct {
+	Handler Handler
+	// ParserOptions controls how requests are parsed, including their size
+	// limits.
+	ParserOptions request.ParserOptions
+
+	// ReadHeaderTimeout bounds reading the request line and headers,
+	// counting from the first byte of the request. A client that runs out
+	// of time gets a 408 and the connection is closed. Zero means
+	// ReadTimeout is used instead.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout bounds reading a whole request, body included, counting
+	// from its first byte. Zero means no limit.
+	ReadTimeout time.Duration
+	// WriteTimeout bounds writing the response, counting from the end of
+	// the request headers. Zero means no limit.
+	WriteTimeout time.Duration
+	// IdleTimeout bounds how long a persistent connection may wait for its
+	// next request. Zero means the header timeout is used instead.
+	IdleTimeout time.Duration
+	// ExpectContinue, when set, decides whether a request sent with
+	// "Expect: 100-continue" may send its body. It sees the headers only.
+	// Returning StatusContinue accepts the body; any other status, such as
+	// StatusExpectationFailed or StatusContentTooLarge, is sent as the
+	// final response without reading the body, and the connection closes.
+	// Without it every body is accepted, and the 100 Continue goes out when
+	// the body is first read, so a handler streaming bodies can still turn
+	// a request down by answering without reading it.
+	ExpectContinue func(req *request.Request) response.StatusCode
+	// TLSConfig makes Listen serve HTTPS when set. It needs at least one
+	// certificate; see ListenTLS and SelfSignedCertificate.
+	TLSConfig *tls.Config
+
+	listener net.Listener
+	closed   atomic.Bool
+	// wg tracks the accept loop and every connection still being handled.
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// idleConns holds the connections waiting for their next request, which
+	// Close can shut without interrupting a response.
+	idleConns map[net.Conn]struct{}
+}
+
+// Serve starts a server with the default settings on port. It returns as
+// soon as the listener is ready; use Close to stop the server. Port 0 picks a
+// free port, see Addr.
+func Serve(port int, handler Handler) (*Server, error) {
+	s := NewServer(handler)
+	if err := s.Listen(port); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewServer returns a server with the default settings. Change its fields
+// before calling Listen.
+func NewServer(handler Handler) *Server {
+	return &Server{
+		Handler:           handler,
+		ParserOptions:     request.DefaultParserOptions(),
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ReadTimeout:       defaultReadTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		idleConns:         make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen starts listening on port and handles each connection in its own
+// goroutine. It returns as soon as the listener is ready.
+func (s *Server) Listen(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("error starting TCP listener: %w", err)
+	}
+	if s.TLSConfig != nil {
+		listener = tls.NewListener(listener, s.TLSConfig)
+	}
+	s.listener = listener
+	s.wg.Add(1)
+	go s.listen()
+	return nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops accepting new connections, closes the ones waiting for their
+// next request, and waits for the requests already being handled to finish.
+func (s *Server) Close() error {
+	if s.closed.Swap(true) {
+		return nil
+	}
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.idleConns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// setIdle records whether conn is waiting for its next request. It returns
+// false if the server is closing and the connection should not wait.
+func (s *Server) setIdle(conn net.Conn, idle bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !idle {
+		delete(s.idleConns, conn)
+		return true
+	}
+	if s.closed.Load() {
+		return false
+	}
+	s.idleConns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) listen() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !s.closed.Load() {
+				fmt.Fprintf(os.Stderr, "Error accepting connection: %v\n", err)
+			}
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle serves requests from conn one after another, in the order they
+// arrive, until either side asks to close the connection.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := request.NewReader(conn)
+	reader.Options = s.ParserOptions
+	// Bodies are always read through the stream, so that the header and
+	// body reads can have their own deadlines. A body the server buffers
+	// for the handler keeps the limit of a buffered one.
+	reader.Options.StreamBody = true
+	if !s.ParserOptions.StreamBody {
+		reader.Options.Limits.MaxStreamBodyBytes = s.ParserOptions.Limits.MaxBodyBytes
+	}
+	for first := true; ; first = false {
+		if !s.setIdle(conn, true) {
+			return
+		}
+		idleTimeout := s.IdleTimeout
+		if first || idleTimeout == 0 {
+			idleTimeout = s.readHeaderTimeout()
+		}
+		conn.SetReadDeadline(deadline(time.Now(), idleTimeout))
+		if err := reader.WaitForRequest(); err != nil {
+			// The client went away, or stayed idle for too long.
+			s.setIdle(conn, false)
+			return
+		}
+		start := time.Now()
+		conn.SetReadDeadline(deadline(start, s.readHeaderTimeout()))
+		req, err := reader.ReadRequest()
+		s.setIdle(conn, false)
+		var continued *continueReader
+		if err == nil {
+			conn.SetReadDeadline(deadline(start, s.ReadTimeout))
+			conn.SetWriteDeadline(deadline(time.Now(), s.WriteTimeout))
+			if status, err := s.checkExpect(req); err != nil {
+				rejectExpectation(conn, status, err)
+				return
+			}
+			if req.ExpectsContinue() {
+				continued = &continueReader{conn: conn, body: req.BodyReader}
+				req.BodyReader = continued
+			}
+			if !s.ParserOptions.StreamBody {
+				err = readBody(req)
+			}
+		}
+		if err != nil {
+			s.rejectRequest(conn, err)
+			return
+		}
+		req.RemoteAddr = conn.RemoteAddr().String()
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			state := tlsConn.ConnectionState()
+			req.TLS = &state
+		}
+		w := response.NewWriter(conn)
+		w.SetKeepAlive(req.KeepAlive())
+		w.SetRequestVersion(req.RequestLine.HttpVersion)
+		s.Handler(w, req)
+		if !w.KeepAlive() || (continued != nil && !continued.sent) {
+			return
+		}
+		if req.BodyReader != nil {
+			// Discard what the handler left of the body while it is still
+			// this request's read, so it does not eat into the idle wait
+			// for the next one.
+			conn.SetReadDeadline(deadline(start, s.ReadTimeout))
+			if err := req.BodyReader.Close(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readHeaderTimeout returns ReadHeaderTimeout, or ReadTimeout if it is zero.
+func (s *Server) readHeaderTimeout() time.Duration {
+	if s.ReadHeaderTimeout != 0 {
+		return s.ReadHeaderTimeout
+	}
+	return s.ReadTimeout
+}
+
+// deadline returns the time timeout after start, or no deadline for a zero
+// timeout.
+func deadline(start time.Time, timeout time.Duration) time.Time {
+	if timeout == 0 {
+		return time.Time{}
+	}
+	return start.Add(timeout)
+}
+
+// readBody reads the whole body of a request read in streaming mode into
+// Body, as handlers expect unless the server streams bodies.
+func readBody(req *request.Request) error {
+	body, err := io.ReadAll(req.BodyReader)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		req.Body = body
+	}
+	req.BodyReader = nil
+	return nil
+}
+
+// rejectRequest answers a request that could not be read. Only a request
+// that failed to parse, or arrived too slowly, gets an answer; any other
+// read error means the client is gone.
+func (s *Server) rejectRequest(conn net.Conn, err error) {
+	var parseErr *request.ParseError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &parseErr):
+		conn.SetWriteDeadline(time.Now().Add(errorWriteTimeout))
+		writeError(response.NewWriter(conn), response.StatusCode(parseErr.StatusCode), parseErr.Err)
+		lingeringClose(conn)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		conn.SetWriteDeadline(time.Now().Add(errorWriteTimeout))
+		writeError(response.NewWriter(conn), response.StatusRequestTimeout, errors.New("request timed out"))
+	}
+}
+
+// Bounds on how long and how much lingeringClose reads before giving up.
+const (
+	lingerTimeout  = 500 * time.Millisecond
+	lingerMaxBytes = 256 << 10
+)
+
+// lingeringClose stops writing and discards what the client is still
+// sending. Closing a socket with unread data makes the kernel reset the
+// connection, which can destroy an error response before the client reads it.
+func lingeringClose(conn net.Conn) {
+	// Both *net.TCPConn and *tls.Conn can shut down just the write side.
+	halfCloser, ok := conn.(interface{ CloseWrite() error })
+	if !ok {
+		return
+	}
+	halfCloser.CloseWrite()
+	conn.SetReadDeadline(time.Now().Add(lingerTimeout))
+	io.Copy(io.Discard, io.LimitReader(conn, lingerMaxBytes))
+}
+
+// writeError answers with statusCode and the error text as a plain-text body.
+func writeError(w *response.Writer, statusCode response.StatusCode, err error) {
+	body := []byte(err.Error() + "\n")
+	if err := w.WriteStatusLine(statusCode); err != nil {
+		return
+	}
+	if err := w.WriteHeaders(response.GetDefaultHeaders(len(body))); err != nil {
+		return
+	}
+	w.WriteBody(body)
+}
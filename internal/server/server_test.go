@@ -0,0 +1,325 @@
+package server
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func helloHandler(w *response.Writer, req *request.Request) {
+	body := []byte("hello " + req.RequestLine.RequestTarget)
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+// roundTrip sends raw to the server and returns everything it answers with
+// until it closes the connection.
+func roundTrip(t *testing.T, s *Server, raw string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(raw))
+	require.NoError(t, err)
+	got, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	return string(got)
+}
+
+func TestServe(t *testing.T) {
+	s, err := Serve(0, helloHandler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: Handler answers a valid request
+	got := roundTrip(t, s, "GET /coffee HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Content-Length: 13\r\nConnection: close\r\nContent-Type: text/plain\r\n\r\n"+
+		"hello /coffee", got)
+
+	// Test: Malformed request gets a 400 without reaching the handler
+	got = roundTrip(t, s, "GET /coffee\r\n\r\n")
+	assert.Contains(t, got, "HTTP/1.1 400 Bad Request\r\n")
+}
+
+func TestClose(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s, err := Serve(0, func(w *response.Writer, req *request.Request) {
+		close(started)
+		<-release
+		helloHandler(w, req)
+	})
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	// Test: Close waits for the in-flight connection
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the handler finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-closed
+
+	got, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "hello /")
+
+	// Test: No new connections after Close
+	_, err = net.Dial("tcp", s.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestKeepAlive(t *testing.T) {
+	s, err := Serve(0, helloHandler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: Pipelined requests are answered in order on one connection
+	got := roundTrip(t, s, "GET /one HTTP/1.1\r\n\r\n"+
+		"POST /two HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello"+
+		"GET /three HTTP/1.1\r\nConnection: close\r\n\r\n")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Content-Length: 10\r\nConnection: keep-alive\r\nContent-Type: text/plain\r\n\r\n"+
+		"hello /one"+
+		"HTTP/1.1 200 OK\r\n"+
+		"Content-Length: 10\r\nConnection: keep-alive\r\nContent-Type: text/plain\r\n\r\n"+
+		"hello /two"+
+		"HTTP/1.1 200 OK\r\n"+
+		"Content-Length: 12\r\nConnection: close\r\nContent-Type: text/plain\r\n\r\n"+
+		"hello /three", got)
+
+	// Test: HTTP/1.0 closes after one response
+	got = roundTrip(t, s, "GET /old HTTP/1.0\r\n\r\n")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Content-Length: 10\r\nConnection: close\r\nContent-Type: text/plain\r\n\r\n"+
+		"hello /old", got)
+
+	// Test: HTTP/1.0 with keep-alive stays open
+	got = roundTrip(t, s, "GET /a HTTP/1.0\r\nConnection: keep-alive\r\n\r\n"+
+		"GET /b HTTP/1.0\r\n\r\n")
+	assert.Equal(t, 2, strings.Count(got, "HTTP/1.1 200 OK"))
+}
+
+func TestCloseIdleConnection(t *testing.T) {
+	s, err := Serve(0, helloHandler)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	buf := make([]byte, 1024)
+	_, err = conn.Read(buf)
+	require.NoError(t, err)
+
+	// Test: Close does not wait for a connection with no request in flight
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for an idle connection")
+	}
+}
+
+func TestLimitErrors(t *testing.T) {
+	s := NewServer(helloHandler)
+	s.ParserOptions.Limits = request.Limits{
+		MaxRequestLineBytes: 32,
+		MaxHeaderBytes:      64,
+		MaxHeaderCount:      2,
+		MaxBodyBytes:        4,
+	}
+	require.NoError(t, s.Listen(0))
+	defer s.Close()
+
+	// Test: Long request line
+	got := roundTrip(t, s, "GET /"+strings.Repeat("a", 64)+" HTTP/1.1\r\n\r\n")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 414 URI Too Long\r\n"), got)
+
+	// Test: Too many headers
+	got = roundTrip(t, s, "GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 431 Request Header Fields Too Large\r\n"), got)
+
+	// Test: Body too large
+	got = roundTrip(t, s, "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 413 Content Too Large\r\n"), got)
+	got = roundTrip(t, s, "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 413 Content Too Large\r\n"), got)
+
+	// Test: A server streaming bodies leaves them to MaxStreamBodyBytes
+	streaming := NewServer(helloHandler)
+	streaming.ParserOptions.Limits = s.ParserOptions.Limits
+	streaming.ParserOptions.StreamBody = true
+	require.NoError(t, streaming.Listen(0))
+	defer streaming.Close()
+	got = roundTrip(t, streaming, "POST / HTTP/1.1\r\nContent-Length: 5\r\nConnection: close\r\n\r\nhello")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 200 OK\r\n"), got)
+}
+
+func TestParseErrorStatus(t *testing.T) {
+	s, err := Serve(0, helloHandler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: Unsupported version gets a 505
+	got := roundTrip(t, s, "GET / HTTP/2.0\r\n\r\n")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 505 HTTP Version Not Supported\r\n"), got)
+
+	// Test: Unknown transfer coding gets a 501
+	got = roundTrip(t, s, "POST / HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n")
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 501 Not Implemented\r\n"), got)
+}
+
+// readUntilClose reads what the server sends until it closes conn, failing
+// the test if that takes longer than limit.
+func readUntilClose(t *testing.T, conn net.Conn, limit time.Duration) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(limit))
+	got, err := io.ReadAll(conn)
+	require.NoError(t, err, "server did not close the connection in time")
+	return string(got)
+}
+
+func TestTimeouts(t *testing.T) {
+	s := NewServer(helloHandler)
+	s.ReadHeaderTimeout = 100 * time.Millisecond
+	s.ReadTimeout = 200 * time.Millisecond
+	s.IdleTimeout = 100 * time.Millisecond
+	require.NoError(t, s.Listen(0))
+	defer s.Close()
+
+	// Test: Headers arriving too slowly get a 408
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: local"))
+	require.NoError(t, err)
+	got := readUntilClose(t, conn, time.Second)
+	assert.Contains(t, got, "HTTP/1.1 408 Request Timeout\r\n")
+	assert.Contains(t, got, "Connection: close\r\n")
+
+	// Test: Body arriving too slowly gets a 408
+	conn, err = net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nContent-Length: 10\r\n\r\nhalf"))
+	require.NoError(t, err)
+	got = readUntilClose(t, conn, time.Second)
+	assert.Contains(t, got, "HTTP/1.1 408 Request Timeout\r\n")
+
+	// Test: An idle connection is closed without a response
+	conn, err = net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /first HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	got = readUntilClose(t, conn, time.Second)
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 200 OK\r\n"), got)
+	assert.NotContains(t, got, "408")
+
+	// Test: A connection sending requests in time stays open
+	conn, err = net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := response.NewReader(conn)
+	for range 3 {
+		time.Sleep(50 * time.Millisecond)
+		_, err = conn.Write([]byte("GET /again HTTP/1.1\r\n\r\n"))
+		require.NoError(t, err)
+		resp, err := reader.ReadResponse("GET")
+		require.NoError(t, err)
+		assert.Equal(t, "hello /again", string(resp.Body))
+	}
+}
+
+func TestUnreadBodyDrainedUnderReadTimeout(t *testing.T) {
+	s := NewServer(helloHandler)
+	s.ParserOptions.StreamBody = true
+	s.ReadTimeout = 2 * time.Second
+	s.IdleTimeout = 100 * time.Millisecond
+	require.NoError(t, s.Listen(0))
+	defer s.Close()
+
+	// Test: A body the handler left unread may take longer than the idle
+	// timeout to arrive, and the next request is still served
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST /first HTTP/1.1\r\nContent-Length: 8\r\n\r\n"))
+	require.NoError(t, err)
+	reader := response.NewReader(conn)
+	resp, err := reader.ReadResponse("POST")
+	require.NoError(t, err)
+	assert.Equal(t, "hello /first", string(resp.Body))
+	for range 4 {
+		time.Sleep(60 * time.Millisecond)
+		_, err = conn.Write([]byte("ab"))
+		require.NoError(t, err)
+	}
+	_, err = conn.Write([]byte("GET /second HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err = reader.ReadResponse("GET")
+	require.NoError(t, err)
+	assert.Equal(t, "hello /second", string(resp.Body))
+}
+
+func TestWriteTimeout(t *testing.T) {
+	done := make(chan error, 1)
+	s := NewServer(func(w *response.Writer, req *request.Request) {
+		h := response.GetDefaultHeaders(0)
+		h.Del("content-length")
+		w.WriteStatusLine(response.StatusOK)
+		w.WriteHeaders(h)
+		chunk := make([]byte, 64<<10)
+		for {
+			if _, err := w.WriteChunkedBody(chunk); err != nil {
+				done <- err
+				return
+			}
+		}
+	})
+	s.WriteTimeout = 100 * time.Millisecond
+	require.NoError(t, s.Listen(0))
+	defer s.Close()
+
+	// Test: A client that stops reading makes the handler's writes fail
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	select {
+	case err := <-done:
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	case <-time.After(2 * time.Second):
+		t.Fatal("write did not time out")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madhu1992blue/httpfromtcp/internal/client"
+	"github.com/madhu1992blue/httpfromtcp/internal/request"
+	"github.com/madhu1992blue/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tlsInfoHandler answers with the TLS details of the request.
+func tlsInfoHandler(w *response.Writer, req *request.Request) {
+	body := "plain"
+	if req.TLS != nil {
+		body = fmt.Sprintf("%s %s %s", tls.VersionName(req.TLS.Version), tls.CipherSuiteName(req.TLS.CipherSuite), req.TLS.ServerName)
+	}
+	w.WriteStatusLine(response.StatusOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody([]byte(body))
+}
+
+// dialTLS connects to s trusting cert, asking for serverName.
+func dialTLS(t *testing.T, s *Server, cert tls.Certificate, serverName string, maxVersion uint16) *client.Client {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+	conn, err := tls.Dial("tcp", s.Addr().String(), &tls.Config{
+		RootCAs:    roots,
+		ServerName: serverName,
+		MaxVersion: maxVersion,
+	})
+	require.NoError(t, err)
+	return client.NewClient(conn)
+}
+
+func TestSelfSignedTLS(t *testing.T) {
+	cert, err := SelfSignedCertificate("localhost", "127.0.0.1")
+	require.NoError(t, err)
+	s := NewServer(tlsInfoHandler)
+	s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	require.NoError(t, s.Listen(0))
+	defer s.Close()
+
+	// Test: Request over TLS 1.3 exposes version, cipher and SNI
+	c := dialTLS(t, s, cert, "localhost", 0)
+	defer c.Close()
+	resp, err := c.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Regexp(t, `^TLS 1\.3 TLS_\w+ localhost$`, string(resp.Body))
+
+	// Test: Persistent connection over TLS
+	resp, err = c.Do(client.NewRequest("GET", "/again", nil))
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+
+	// Test: Older TLS version is reported as negotiated
+	c12 := dialTLS(t, s, cert, "localhost", tls.VersionTLS12)
+	defer c12.Close()
+	resp, err = c12.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Regexp(t, `^TLS 1\.2 TLS_ECDHE_ECDSA_\w+ localhost$`, string(resp.Body))
+
+	// Test: IP address without SNI
+	cIP := dialTLS(t, s, cert, "127.0.0.1", 0)
+	defer cIP.Close()
+	resp, err = cIP.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Regexp(t, `^TLS 1\.3 TLS_\w+ $`, string(resp.Body))
+}
+
+func TestListenTLS(t *testing.T) {
+	cert, err := SelfSignedCertificate("localhost")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	// Test: Certificate and key loaded from files
+	s := NewServer(tlsInfoHandler)
+	require.NoError(t, s.ListenTLS(0, certFile, keyFile))
+	defer s.Close()
+	c := dialTLS(t, s, cert, "localhost", 0)
+	defer c.Close()
+	resp, err := c.Do(client.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	assert.Contains(t, string(resp.Body), "localhost")
+
+	// Test: Missing files
+	err = NewServer(tlsInfoHandler).ListenTLS(0, filepath.Join(dir, "missing.pem"), keyFile)
+	assert.Error(t, err)
+
+	// Test: Plain connections report no TLS state
+	plain, err := Serve(0, tlsInfoHandler)
+	require.NoError(t, err)
+	defer plain.Close()
+	got := roundTrip(t, plain, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n")
+	assert.Contains(t, got, "\r\n\r\nplain")
+}
@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"errors"
+	"io"
+)
+
+// errBodyClosed is returned by reads from a BodyReader after Close.
+var errBodyClosed = errors.New("read on closed body")
+
+// Message is a message whose body is decoded by its parser as it goes.
+type Message interface {
+	// TakeBody returns the body bytes decoded since the last call. They
+	// stay valid until the next call to ParseBody.
+	TakeBody() []byte
+	// ParseBody parses more of the message, stopping once some body bytes
+	// are decoded or the message is complete.
+	ParseBody() error
+	// Done reports whether the whole message has been parsed.
+	Done() bool
+}
+
+// BodyReader hands out the body of a message as its parser decodes it,
+// stopping at the end of the body.
+type BodyReader struct {
+	msg Message
+	// pending holds decoded body bytes not returned by Read yet.
+	pending []byte
+	err     error
+	closed  bool
+}
+
+func NewBodyReader(msg Message) *BodyReader {
+	return &BodyReader{msg: msg}
+}
+
+func (b *BodyReader) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, errBodyClosed
+	}
+	if len(b.pending) == 0 {
+		b.pending = b.msg.TakeBody()
+	}
+	if len(b.pending) == 0 {
+		if b.msg.Done() {
+			return 0, io.EOF
+		}
+		if b.err != nil {
+			return 0, b.err
+		}
+		b.err = b.msg.ParseBody()
+		b.pending = b.msg.TakeBody()
+		if len(b.pending) == 0 {
+			if b.err != nil {
+				return 0, b.err
+			}
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+// Close discards the rest of the body so the next message on the stream can
+// be read.
+func (b *BodyReader) Close() error {
+	if b.closed {
+		return b.err
+	}
+	if _, err := io.Copy(io.Discard, b); err != nil {
+		b.err = err
+	}
+	b.closed = true
+	return b.err
+}
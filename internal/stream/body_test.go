@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkMessage decodes one chunk of its body per ParseBody call, and can
+// fail after the chunks run out.
+type chunkMessage struct {
+	chunks  []string
+	pending []byte
+	err     error
+}
+
+func (m *chunkMessage) TakeBody() []byte {
+	p := m.pending
+	m.pending = m.pending[:0]
+	return p
+}
+
+func (m *chunkMessage) ParseBody() error {
+	if len(m.chunks) == 0 {
+		return m.err
+	}
+	m.pending = append(m.pending, m.chunks[0]...)
+	m.chunks = m.chunks[1:]
+	return nil
+}
+
+func (m *chunkMessage) Done() bool {
+	return len(m.chunks) == 0 && m.err == nil
+}
+
+func TestBodyReader(t *testing.T) {
+	// Test: Bytes decoded before the first Read come first
+	msg := &chunkMessage{chunks: []string{"lo, ", "world"}, pending: []byte("hel")}
+	body, err := io.ReadAll(NewBodyReader(msg))
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(body))
+
+	// Test: Small reads take a chunk in several pieces
+	body = nil
+	r := NewBodyReader(&chunkMessage{chunks: []string{"abcdef"}})
+	buf := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		body = append(body, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "abcdef", string(body))
+
+	// Test: A parse error is returned once the decoded bytes are read, and
+	// again on later reads
+	parseErr := errors.New("bad chunk")
+	r = NewBodyReader(&chunkMessage{chunks: []string{"ab"}, err: parseErr})
+	body, err = io.ReadAll(r)
+	assert.Equal(t, "ab", string(body))
+	assert.Equal(t, parseErr, err)
+	_, err = r.Read(buf)
+	assert.Equal(t, parseErr, err)
+
+	// Test: Close discards the rest, and reads after it fail
+	r = NewBodyReader(&chunkMessage{chunks: []string{"ab", "cd"}})
+	require.NoError(t, r.Close())
+	_, err = r.Read(buf)
+	assert.ErrorIs(t, err, errBodyClosed)
+}
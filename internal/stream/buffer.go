@@ -0,0 +1,126 @@
+// Package stream holds the reading side shared by the request and response
+// readers: a buffer feeding bytes from a connection to an incremental
+// parser, and the BodyReader that pulls a streamed body through it.
+package stream
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// poolBufferSize is the size of the pooled read buffers, enough for the
+// whole header section of most messages.
+const poolBufferSize = 4 << 10
+
+// bufferPool holds read buffers not in use by any Buffer. A Buffer takes one
+// when it starts reading and gives it back on Release, so an idle
+// connection holds no buffer.
+var bufferPool = sync.Pool{
+	New: func() any { return new([poolBufferSize]byte) },
+}
+
+// Buffer keeps the bytes read from a stream that have not been parsed yet.
+// Bytes read past the end of one message stay buffered for the next, so
+// pipelined messages are not lost.
+type Buffer struct {
+	reader io.Reader
+	// buf[start:end] holds the bytes read but not parsed yet. buf is nil
+	// while the Buffer holds no buffer from the pool.
+	buf    []byte
+	start  int
+	end    int
+	sawEOF bool
+}
+
+// NewBuffer returns a Buffer reading from r. It is returned by value so
+// that a reader can embed it without another allocation.
+func NewBuffer(r io.Reader) Buffer {
+	return Buffer{reader: r}
+}
+
+// Buffered returns the number of bytes read but not parsed yet.
+func (b *Buffer) Buffered() int {
+	return b.end - b.start
+}
+
+// ParseUntil feeds the buffered bytes to parse, which returns how many it
+// consumed, reading more from the stream whenever they run out, until stop
+// reports true. If the stream ends first, it returns what atEOF returns.
+func (b *Buffer) ParseUntil(parse func(data []byte) (int, error), stop func() bool, atEOF func() error) error {
+	for {
+		n, err := parse(b.buf[b.start:b.end])
+		if err != nil {
+			return err
+		}
+		b.start += n
+		if b.start == b.end {
+			b.start, b.end = 0, 0
+		}
+		if stop() {
+			return nil
+		}
+		if b.sawEOF {
+			return atEOF()
+		}
+		if err := b.fill(); err != nil {
+			return err
+		}
+	}
+}
+
+// Wait blocks until at least one byte is buffered. It returns io.EOF if the
+// stream ends first.
+func (b *Buffer) Wait() error {
+	for b.start == b.end {
+		if b.sawEOF {
+			return io.EOF
+		}
+		if err := b.fill(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fill reads more of the stream into the buffer. It takes a buffer from the
+// pool if it has none, and makes room at the end by sliding the unparsed
+// bytes to the front or, when they fill the whole buffer, by growing it.
+func (b *Buffer) fill() error {
+	if b.buf == nil {
+		b.buf = bufferPool.Get().(*[poolBufferSize]byte)[:]
+	}
+	if b.end == len(b.buf) && b.start > 0 {
+		b.end = copy(b.buf, b.buf[b.start:b.end])
+		b.start = 0
+	}
+	if b.end == len(b.buf) {
+		newBuf := make([]byte, len(b.buf)*2)
+		copy(newBuf, b.buf)
+		putBuffer(b.buf)
+		b.buf = newBuf
+	}
+	n, err := b.reader.Read(b.buf[b.end:])
+	b.end += n
+	if err == io.EOF {
+		b.sawEOF = true
+	} else if err != nil {
+		return fmt.Errorf("error reading from reader: %w", err)
+	}
+	return nil
+}
+
+// Release gives the buffer back to the pool, dropping any bytes left in it.
+func (b *Buffer) Release() {
+	putBuffer(b.buf)
+	b.buf = nil
+	b.start, b.end = 0, 0
+}
+
+// putBuffer returns buf to the pool. A buffer grown past the pooled size is
+// left to the garbage collector instead.
+func putBuffer(buf []byte) {
+	if cap(buf) == poolBufferSize {
+		bufferPool.Put((*[poolBufferSize]byte)(buf[:poolBufferSize]))
+	}
+}
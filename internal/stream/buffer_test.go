@@ -0,0 +1,109 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkReader struct {
+	data            string
+	numBytesPerRead int
+	pos             int
+}
+
+// Read reads up to len(p) or numBytesPerRead bytes from the string per call,
+// simulating a network connection delivering data in pieces.
+func (cr *chunkReader) Read(p []byte) (n int, err error) {
+	if cr.pos >= len(cr.data) {
+		return 0, io.EOF
+	}
+	endIndex := min(cr.pos+cr.numBytesPerRead, len(cr.data))
+	n = copy(p, cr.data[cr.pos:endIndex])
+	cr.pos += n
+	return n, nil
+}
+
+// readLine parses one CRLF-terminated line from b.
+func readLine(b *Buffer) (string, error) {
+	var line string
+	parse := func(data []byte) (int, error) {
+		i := bytes.Index(data, []byte("\r\n"))
+		if i == -1 {
+			return 0, nil
+		}
+		line = string(data[:i])
+		return i + 2, nil
+	}
+	err := b.ParseUntil(parse, func() bool { return line != "" }, func() error {
+		return io.ErrUnexpectedEOF
+	})
+	return line, err
+}
+
+func TestBuffer(t *testing.T) {
+	// Test: A line larger than the pooled buffer grows it without losing
+	// the bytes already read
+	long := strings.Repeat("x", 3*poolBufferSize)
+	b := NewBuffer(&chunkReader{data: long + "\r\nnext\r\n", numBytesPerRead: 1000})
+	line, err := readLine(&b)
+	require.NoError(t, err)
+	assert.Equal(t, long, line)
+	line, err = readLine(&b)
+	require.NoError(t, err)
+	assert.Equal(t, "next", line)
+
+	// Test: Lines that straddle the end of the buffer slide to the front
+	one := "abcdefghijklmnopqrstuvwxyz\r\n"
+	count := 3 * poolBufferSize / len(one)
+	b = NewBuffer(&chunkReader{data: strings.Repeat(one, count), numBytesPerRead: 777})
+	for range count {
+		line, err = readLine(&b)
+		require.NoError(t, err)
+		require.Equal(t, one[:len(one)-2], line)
+	}
+	assert.Equal(t, poolBufferSize, len(b.buf))
+
+	// Test: The end of the stream goes to atEOF
+	_, err = readLine(&b)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// Test: Read errors are wrapped
+	readErr := errors.New("connection reset")
+	b = NewBuffer(io.MultiReader(strings.NewReader("par"), &failingReader{err: readErr}))
+	_, err = readLine(&b)
+	assert.ErrorIs(t, err, readErr)
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestBufferWaitAndRelease(t *testing.T) {
+	// Test: Wait returns once a byte has arrived, without consuming it
+	b := NewBuffer(strings.NewReader("one\r\ntwo\r\n"))
+	assert.Nil(t, b.buf)
+	require.NoError(t, b.Wait())
+	assert.NotNil(t, b.buf)
+	line, err := readLine(&b)
+	require.NoError(t, err)
+	assert.Equal(t, "one", line)
+	assert.Equal(t, 5, b.Buffered())
+
+	// Test: Release drops the buffer and what is left in it
+	b.Release()
+	assert.Nil(t, b.buf)
+	assert.Equal(t, 0, b.Buffered())
+
+	// Test: Wait at the end of the stream
+	assert.Equal(t, io.EOF, b.Wait())
+}